@@ -0,0 +1,168 @@
+package e2e
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"observability-demo/lib"
+	"observability-demo/lib/telemetrytest"
+	"strings"
+	"testing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// flow is the span tree of a set or get through all services, method is the HTTP method between them.
+func flow(op, method, handle string) telemetrytest.Tree {
+	server := func(children ...telemetrytest.Tree) telemetrytest.Tree {
+		return telemetrytest.Tree{Name: "/", Children: []telemetrytest.Tree{{
+			Name: "in-controller-entry",
+			Children: []telemetrytest.Tree{{
+				Name:     "in-handle-" + handle,
+				Children: children,
+			}},
+		}}}
+	}
+	call := func(server telemetrytest.Tree) telemetrytest.Tree {
+		return telemetrytest.Tree{Name: "HTTP " + method, Children: []telemetrytest.Tree{server}}
+	}
+
+	service2 := server(telemetrytest.Tree{Name: "in-store-" + op})
+	service1 := server(telemetrytest.Tree{Name: "in-client-" + op, Children: []telemetrytest.Tree{call(service2)}})
+
+	return telemetrytest.Tree{Name: "/", Children: []telemetrytest.Tree{{
+		Name:     op,
+		Children: []telemetrytest.Tree{call(service1)},
+	}}}
+}
+
+func TestSetAndGet(t *testing.T) {
+	h := Start(t)
+	h.Tenant = "acme"
+
+	set := h.Set(t, "foo", "bar")
+	set.AssertStatus(t, http.StatusOK)
+	h.AssertTrace(t, set, flow("set", http.MethodPost, "post"))
+
+	get := h.Get(t, "foo")
+	get.AssertStatus(t, http.StatusOK)
+	get.AssertBody(t, "bar")
+	h.AssertTrace(t, get, flow("get", http.MethodGet, "get"))
+
+	for _, r := range []Response{set, get} {
+		assertCorrelated(t, h, r, "request completed", "ui", "service-1", "service-2")
+	}
+	assertCorrelated(t, h, set, "set key", "service-2")
+	assertCorrelated(t, h, get, "found key", "service-2")
+
+	assertSample(t, h.UI, `http_server_requests_total{code="200",experiment="",method="POST",route="/set",tenant="acme"} 1.0`)
+	assertSample(t, h.UI, `http_server_requests_total{code="200",experiment="",method="GET",route="/get",tenant="acme"} 1.0`)
+	assertSample(t, h.Service1, `http_server_requests_total{code="204",experiment="",method="POST",route="/",tenant="acme"} 1.0`)
+	assertSample(t, h.Service1, `http_server_requests_total{code="200",experiment="",method="GET",route="/",tenant="acme"} 1.0`)
+	assertSample(t, h.Service2, `http_server_requests_total{code="200",experiment="",method="POST",route="/",tenant="acme"} 1.0`)
+	assertSample(t, h.Service2, `store_operations_total{operation="set"} 1.0`)
+	assertSample(t, h.Service2, `store_operations_total{operation="get"} 1.0`)
+	assertSample(t, h.Service2, `store_hits_total 1.0`)
+	assertSample(t, h.Service2, `store_keys 1.0`)
+
+	// Reading the store directly counts as another get, so it comes last.
+	h.AssertStored(t, "foo", "bar")
+}
+
+func TestSingleTrace(t *testing.T) {
+	h := Start(t)
+
+	set := h.Set(t, "foo", "bar")
+	set.AssertStatus(t, http.StatusOK)
+	h.AssertTrace(t, set, flow("set", http.MethodPost, "post"))
+
+	// Every span of the request is part of the trace returned by the ui, none of the services started its own.
+	spans := telemetrytest.Spans(h.Spans)
+	if trees := telemetrytest.Trees(spans); len(trees) != 1 {
+		t.Fatalf("expected a single trace, got %d span trees", len(trees))
+	}
+	services := make(map[string]bool)
+	for _, span := range spans {
+		if span.SpanContext().TraceID() != set.TraceID {
+			t.Fatalf("span %s is not part of trace %s", span.Name(), set.TraceID)
+		}
+		if name, ok := span.Resource().Set().Value(semconv.ServiceNameKey); ok {
+			services[name.AsString()] = true
+		}
+	}
+	for _, service := range []string{"ui", "service-1", "service-2"} {
+		if !services[service] {
+			t.Errorf("trace %s has no spans of %s", set.TraceID, service)
+		}
+	}
+}
+
+func TestGetMissingKey(t *testing.T) {
+	h := Start(t)
+	h.Tenant = "acme"
+
+	// The ui renders the miss of service-1 into the page.
+	get := h.Get(t, "missing")
+	get.AssertStatus(t, http.StatusOK)
+	get.AssertBody(t, "key not found")
+	h.AssertTrace(t, get, flow("get", http.MethodGet, "get"))
+
+	// A miss fails no server or internal span, only the HTTP client spans mark the 404 as error like the
+	// semantic conventions of client spans require.
+	for _, span := range telemetrytest.TraceSpans(h.Spans, get.TraceID) {
+		if span.SpanKind() != trace.SpanKindClient {
+			telemetrytest.AssertNotError(t, span)
+		}
+	}
+	telemetrytest.AssertSpan(t, h.Spans, "in-store-get", lib.KeyKey.String("missing"), lib.KeyFoundKey.Bool(false))
+
+	assertCorrelated(t, h, get, "request completed", "ui", "service-1", "service-2")
+	assertSample(t, h.Service2, `store_misses_total 1.0`)
+}
+
+// assertCorrelated fails the test unless each service logged msg with the request ID, trace ID and tenant of the request.
+// h.Tenant has to be set, requests of the default namespace carry no baggage.
+func assertCorrelated(t *testing.T, h *Harness, r Response, msg string, services ...string) {
+	t.Helper()
+
+	entries := h.RequestLogs(r)
+	for _, service := range services {
+		found := false
+		for _, entry := range entries {
+			fields := entry.ContextMap()
+			if fields["component"] != service || entry.Message != msg {
+				continue
+			}
+			found = true
+
+			if fields["trace_id"] != r.TraceID.String() {
+				t.Errorf("%s logged %q with trace_id %v, expected %s", service, msg, fields["trace_id"], r.TraceID)
+			}
+			// The store logs the namespace, the access logs the baggage the tenant was taken from.
+			tenant, ok := fields["tenant"]
+			if !ok {
+				tenant = fields[lib.BaggageAttributePrefix+lib.TenantBaggageKey]
+			}
+			if tenant != h.Tenant {
+				t.Errorf("%s logged %q with tenant %v, expected %s", service, msg, tenant, h.Tenant)
+			}
+		}
+		if !found {
+			t.Errorf("%s did not log %q with request_id %s", service, msg, r.RequestID)
+		}
+	}
+}
+
+// assertSample fails the test unless the metrics of the service contain the sample.
+func assertSample(t *testing.T, srv *httptest.Server, sample string) {
+	t.Helper()
+
+	exposition := telemetrytest.ScrapeOpenMetrics(t, srv.URL+lib.MetricsPath)
+	for _, line := range strings.Split(exposition, "\n") {
+		if line == sample || strings.HasPrefix(line, sample+" ") {
+			return
+		}
+	}
+
+	t.Fatalf("metrics of %s do not contain %s", srv.URL, sample)
+}
@@ -0,0 +1,237 @@
+// Package e2e runs service-2, service-1 and the ui in one process on ephemeral ports,
+// so the full request path can be tested without docker compose.
+// All services share one in-memory span exporter and one log observer.
+package e2e
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"observability-demo/internal/service1"
+	"observability-demo/internal/service2"
+	"observability-demo/internal/ui"
+	"observability-demo/lib"
+	"observability-demo/lib/telemetrytest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// spanTimeout is how long assertions wait for server spans, which end after the response was written.
+const spanTimeout = 5 * time.Second
+
+type Harness struct {
+	UI       *httptest.Server
+	Service1 *httptest.Server
+	Service2 *httptest.Server
+
+	// Tenant is sent as tenant baggage member with every request to the ui, empty uses the default namespace.
+	Tenant string
+
+	// Store is the store of service-2, to assert on stored values directly.
+	Store *service2.MemoryStore
+	Spans *tracetest.InMemoryExporter
+	Logs  *observer.ObservedLogs
+}
+
+// Response is a response of the ui, read completely.
+type Response struct {
+	StatusCode int
+	Body       string
+	TraceID    trace.TraceID
+	RequestID  string
+}
+
+// Start starts all services, they are stopped when the test finishes.
+func Start(t testing.TB) *Harness {
+	t.Helper()
+
+	// The HTTP instrumentation uses the global propagator, like in production.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	baggageCfg, err := lib.LoadBaggageConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	h := &Harness{
+		Spans: tracetest.NewInMemoryExporter(),
+		Logs:  logs,
+	}
+	telemetry := func(service string) lib.Telemetry {
+		return lib.Telemetry{
+			Logger:         zap.New(core).With(zap.String("component", service)),
+			TracerProvider: telemetrytest.NewServiceTracerProvider(t, h.Spans, service),
+			Registry:       lib.NewRegistry(),
+			Baggage:        lib.NewBaggage(baggageCfg),
+		}
+	}
+
+	svc2 := service2.New(service2.Config{}, telemetry("service-2"))
+	h.Store = svc2.Store
+	h.Service2 = httptest.NewServer(svc2.Handler)
+	t.Cleanup(h.Service2.Close)
+
+	svc1, err := service1.New(service1.Config{
+		StoreTransport:   service1.TransportHTTP,
+		StoreHTTPAddress: h.Service2.URL,
+	}, telemetry("service-1"))
+	if err != nil {
+		t.Fatalf("failed to start service-1: %s", err)
+	}
+	h.Service1 = httptest.NewServer(svc1.Handler)
+	t.Cleanup(func() {
+		h.Service1.Close()
+		_ = svc1.Close()
+	})
+
+	svcUI := ui.New(ui.Config{
+		ServerAddress:       h.Service1.URL,
+		TraceViewerCapacity: 100,
+	}, telemetry("ui"))
+	h.UI = httptest.NewServer(svcUI.Handler)
+	t.Cleanup(h.UI.Close)
+
+	return h
+}
+
+// Set submits the set form of the ui.
+func (h *Harness) Set(t testing.TB, key, value string) Response {
+	t.Helper()
+
+	form := url.Values{}
+	form.Set("key", key)
+	form.Set("value", value)
+
+	return h.do(t, http.MethodPost, h.UI.URL+"/set", strings.NewReader(form.Encode()))
+}
+
+// Get submits the get form of the ui.
+func (h *Harness) Get(t testing.TB, key string) Response {
+	t.Helper()
+
+	query := url.Values{}
+	query.Set("key", key)
+
+	return h.do(t, http.MethodGet, h.UI.URL+"/get?"+query.Encode(), nil)
+}
+
+func (h *Harness) do(t testing.TB, method, target string, body io.Reader) Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if h.Tenant != "" {
+		req.Header.Set("baggage", lib.TenantBaggageKey+"="+h.Tenant)
+	}
+
+	resp, err := h.UI.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %s", method, target, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	traceID, err := trace.TraceIDFromHex(resp.Header.Get(lib.TraceIDHeader))
+	if err != nil {
+		t.Fatalf("response has no valid %s header: %s", lib.TraceIDHeader, err)
+	}
+
+	return Response{
+		StatusCode: resp.StatusCode,
+		Body:       string(content),
+		TraceID:    traceID,
+		RequestID:  resp.Header.Get(lib.RequestIDHeader),
+	}
+}
+
+// AssertStatus fails the test unless the ui responded with code.
+func (r Response) AssertStatus(t testing.TB, code int) {
+	t.Helper()
+
+	if r.StatusCode != code {
+		t.Fatalf("expected status %d, got %d: %s", code, r.StatusCode, r.Body)
+	}
+}
+
+// AssertBody fails the test unless the rendered page contains substr.
+func (r Response) AssertBody(t testing.TB, substr string) {
+	t.Helper()
+
+	if !strings.Contains(r.Body, substr) {
+		t.Fatalf("expected body to contain %q, got:\n%s", substr, r.Body)
+	}
+}
+
+// AssertStored fails the test unless service-2 stores value for key in the namespace of the tenant.
+func (h *Harness) AssertStored(t testing.TB, key, value string) {
+	t.Helper()
+
+	ctx := t.Context()
+	if h.Tenant != "" {
+		ctx = lib.WithTenant(ctx, h.Tenant)
+	}
+	got, err := h.Store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("key %q is not stored: %s", key, err)
+	}
+	if got != value {
+		t.Fatalf("expected %q to be stored for key %q, got %q", value, key, got)
+	}
+}
+
+// AssertLog fails the test unless the service logged a message containing msg and returns the first match.
+func (h *Harness) AssertLog(t testing.TB, service, msg string) observer.LoggedEntry {
+	t.Helper()
+
+	for _, entry := range h.Logs.All() {
+		if entry.ContextMap()["component"] == service && strings.Contains(entry.Message, msg) {
+			return entry
+		}
+	}
+
+	t.Fatalf("%s did not log %q", service, msg)
+	return observer.LoggedEntry{}
+}
+
+// RequestLogs returns the entries all services logged while serving the request.
+func (h *Harness) RequestLogs(r Response) []observer.LoggedEntry {
+	return h.Logs.Filter(func(entry observer.LoggedEntry) bool {
+		return entry.ContextMap()["request_id"] == r.RequestID
+	}).All()
+}
+
+// AssertTrace fails the test unless the spans of the request's trace form the expected trees.
+func (h *Harness) AssertTrace(t testing.TB, r Response, want ...telemetrytest.Tree) {
+	t.Helper()
+
+	telemetrytest.AssertTraceTree(t, h.Spans, r.TraceID, spanTimeout, want...)
+}
+
+// AssertExemplar fails the test unless the metric of the service links to the request's trace with an exemplar.
+func (h *Harness) AssertExemplar(t testing.TB, service *httptest.Server, metric string, r Response) {
+	t.Helper()
+
+	telemetrytest.AssertExemplar(t, telemetrytest.ScrapeOpenMetrics(t, service.URL+lib.MetricsPath), metric, r.TraceID)
+}
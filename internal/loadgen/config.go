@@ -0,0 +1,89 @@
+// Package loadgen drives service-1 or the ui with a configurable mix of get and set requests
+// and reports latency percentiles and errors.
+package loadgen
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Target string
+
+const (
+	// TargetService1 sends the requests directly to the API of service-1.
+	TargetService1 Target = "service-1"
+	// TargetUI submits the forms of the ui, which adds the ui to every trace.
+	TargetUI Target = "ui"
+)
+
+type Distribution string
+
+const (
+	Uniform Distribution = "uniform"
+	// Zipf makes a few keys hot, like real caches usually see.
+	Zipf Distribution = "zipf"
+)
+
+type Config struct {
+	Target  Target
+	Address string
+	// GetRatio is the share of get requests between 0 and 1, the rest are sets.
+	GetRatio     float64
+	Keys         int
+	Distribution Distribution
+	// ZipfS is the exponent of the zipf distribution, it has to be greater than 1.
+	ZipfS       float64
+	Concurrency int
+	// Rate limits the requests per second over all workers, 0 means as fast as possible.
+	Rate     float64
+	Duration time.Duration
+	Timeout  time.Duration
+	// APIKey authenticates the requests to service-1, it's ignored by the ui.
+	APIKey string
+}
+
+// DefaultAddress returns the address the target listens on when started with make run.
+func DefaultAddress(target Target) string {
+	if target == TargetUI {
+		return "http://localhost:8080"
+	}
+
+	return "http://localhost:4040"
+}
+
+func (c Config) Validate() error {
+	var errs []error
+
+	switch c.Target {
+	case TargetService1, TargetUI:
+	default:
+		errs = append(errs, fmt.Errorf("unknown target %q", c.Target))
+	}
+	switch c.Distribution {
+	case Uniform:
+	case Zipf:
+		if c.ZipfS <= 1 {
+			errs = append(errs, errors.New("zipf exponent has to be greater than 1"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown key distribution %q", c.Distribution))
+	}
+	if c.GetRatio < 0 || c.GetRatio > 1 {
+		errs = append(errs, errors.New("get ratio has to be between 0 and 1"))
+	}
+	if c.Keys <= 0 {
+		errs = append(errs, errors.New("number of keys has to be positive"))
+	}
+	if c.Concurrency <= 0 {
+		errs = append(errs, errors.New("concurrency has to be positive"))
+	}
+	if c.Rate < 0 {
+		errs = append(errs, errors.New("rate must not be negative"))
+	}
+	if c.Duration <= 0 {
+		errs = append(errs, errors.New("duration has to be positive"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,26 @@
+package loadgen
+
+import (
+	"math/rand/v2"
+	"strconv"
+)
+
+// keyChooser picks the key of the next request, it is not safe for concurrent use.
+type keyChooser func() string
+
+func newKeyChooser(cfg Config, rng *rand.Rand) keyChooser {
+	if cfg.Distribution == Zipf {
+		zipf := rand.NewZipf(rng, cfg.ZipfS, 1, uint64(cfg.Keys-1))
+		return func() string {
+			return keyName(int(zipf.Uint64()))
+		}
+	}
+
+	return func() string {
+		return keyName(rng.IntN(cfg.Keys))
+	}
+}
+
+func keyName(i int) string {
+	return "key-" + strconv.Itoa(i)
+}
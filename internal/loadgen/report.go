@@ -0,0 +1,136 @@
+package loadgen
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+type Operation string
+
+const (
+	OperationGet Operation = "get"
+	OperationSet Operation = "set"
+)
+
+// Report collects the results of all requests of a run.
+type Report struct {
+	mu        sync.Mutex
+	latencies map[Operation][]time.Duration
+	// errors counts failed requests by status code or transport error.
+	errors map[Operation]map[string]int
+	misses int
+	start  time.Time
+	end    time.Time
+}
+
+func newReport() *Report {
+	return &Report{
+		latencies: make(map[Operation][]time.Duration),
+		errors:    make(map[Operation]map[string]int),
+		start:     time.Now(),
+	}
+}
+
+func (r *Report) record(op Operation, latency time.Duration, errKind string, miss bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies[op] = append(r.latencies[op], latency)
+	if miss {
+		r.misses++
+	}
+	if errKind == "" {
+		return
+	}
+	if r.errors[op] == nil {
+		r.errors[op] = make(map[string]int)
+	}
+	r.errors[op][errKind]++
+}
+
+func (r *Report) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.end = time.Now()
+}
+
+// Requests returns the number of requests sent for op.
+func (r *Report) Requests(op Operation) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.latencies[op])
+}
+
+// Errors returns the number of failed requests for op.
+func (r *Report) Errors(op Operation) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := 0
+	for _, n := range r.errors[op] {
+		total += n
+	}
+
+	return total
+}
+
+// Print writes a summary with one line per operation.
+func (r *Report) Print(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := r.end.Sub(r.start)
+	total := 0
+	for _, latencies := range r.latencies {
+		total += len(latencies)
+	}
+
+	fmt.Fprintf(w, "%d requests in %s, %.1f req/s, %d gets of missing keys\n\n",
+		total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds(), r.misses)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\trequests\terrors\tp50\tp90\tp99\tmax\t")
+	for _, op := range []Operation{OperationGet, OperationSet} {
+		latencies := slices.Clone(r.latencies[op])
+		if len(latencies) == 0 {
+			continue
+		}
+		slices.Sort(latencies)
+
+		errs := 0
+		for _, n := range r.errors[op] {
+			errs += n
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", op, len(latencies), errs,
+			percentile(latencies, 0.5), percentile(latencies, 0.9), percentile(latencies, 0.99), latencies[len(latencies)-1].Round(time.Microsecond))
+	}
+	_ = tw.Flush()
+
+	for _, op := range []Operation{OperationGet, OperationSet} {
+		kinds := make([]string, 0, len(r.errors[op]))
+		for kind := range r.errors[op] {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			fmt.Fprintf(w, "%s error %s: %d\n", op, kind, r.errors[op][kind])
+		}
+	}
+}
+
+// percentile expects sorted latencies.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	i := int(float64(len(latencies))*p+0.5) - 1
+	i = max(0, min(i, len(latencies)-1))
+
+	return latencies[i].Round(time.Microsecond)
+}
@@ -0,0 +1,218 @@
+package loadgen
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"observability-demo/lib/auth"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Runner sends the requests of a single load test.
+type Runner struct {
+	cfg    Config
+	runID  string
+	client *http.Client
+	tracer trace.Tracer
+	log    *zap.SugaredLogger
+}
+
+// NewRunID returns a random ID to tell the spans of different runs apart.
+func NewRunID() string {
+	var id [8]byte
+	_, _ = crand.Read(id[:])
+
+	return hex.EncodeToString(id[:])
+}
+
+// NewRunner creates a runner, runID is added to every span so the generated load can be found in Tempo.
+func NewRunner(cfg Config, runID string, tp trace.TracerProvider, log *zap.SugaredLogger) *Runner {
+	return &Runner{
+		cfg:   cfg,
+		runID: runID,
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport, otelhttp.WithTracerProvider(tp)),
+			Timeout:   cfg.Timeout,
+			// The ui answers with the rendered page, redirects are not expected.
+			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		tracer: tp.Tracer("loadgen"),
+		log:    log,
+	}
+}
+
+// Run sends requests until the duration elapsed or ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) *Report {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Duration)
+	defer cancel()
+
+	report := newReport()
+	tokens := r.pace(ctx)
+
+	var wg sync.WaitGroup
+	for i := range r.cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rng := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), uint64(i)))
+			nextKey := newKeyChooser(r.cfg, rng)
+
+			for {
+				if tokens != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-tokens:
+					}
+				} else if ctx.Err() != nil {
+					return
+				}
+
+				op := OperationSet
+				if rng.Float64() < r.cfg.GetRatio {
+					op = OperationGet
+				}
+				r.send(ctx, report, op, nextKey(), rng)
+			}
+		}()
+	}
+	wg.Wait()
+	report.finish()
+
+	return report
+}
+
+// pace returns a channel which yields one token per request at the configured rate,
+// or nil if the rate is not limited.
+func (r *Runner) pace(ctx context.Context) <-chan struct{} {
+	if r.cfg.Rate == 0 {
+		return nil
+	}
+
+	tokens := make(chan struct{}, r.cfg.Concurrency)
+	go func() {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / r.cfg.Rate))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Drop the token if all workers are busy, the rate is an upper bound.
+				select {
+				case tokens <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return tokens
+}
+
+func (r *Runner) send(ctx context.Context, report *Report, op Operation, key string, rng *rand.Rand) {
+	ctx, span := r.tracer.Start(ctx, "loadgen "+string(op), trace.WithAttributes(
+		attribute.String("loadgen.run_id", r.runID),
+		attribute.String("loadgen.operation", string(op)),
+		attribute.String("loadgen.target", string(r.cfg.Target)),
+		attribute.String("key", key),
+	))
+	defer span.End()
+
+	req, err := r.newRequest(ctx, op, key, strconv.FormatUint(rng.Uint64(), 36))
+	if err != nil {
+		r.log.Errorw("failed to create request", "error", err)
+		return
+	}
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// Requests still running at the end of the run are not counted.
+		if ctx.Err() != nil {
+			return
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "request failed")
+		report.record(op, time.Since(start), transportError(err), false)
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	latency := time.Since(start)
+
+	// Gets of keys which were never set are expected, service-1 answers them with 404.
+	miss := op == OperationGet && resp.StatusCode == http.StatusNotFound
+	errKind := ""
+	if resp.StatusCode >= http.StatusBadRequest && !miss {
+		errKind = strconv.Itoa(resp.StatusCode)
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	report.record(op, latency, errKind, miss)
+}
+
+func (r *Runner) newRequest(ctx context.Context, op Operation, key, value string) (*http.Request, error) {
+	query := url.Values{}
+	query.Set("key", key)
+
+	switch {
+	case r.cfg.Target == TargetUI && op == OperationGet:
+		return http.NewRequestWithContext(ctx, http.MethodGet, r.cfg.Address+"/get?"+query.Encode(), nil)
+	case r.cfg.Target == TargetUI:
+		query.Set("value", value)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Address+"/set", strings.NewReader(query.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}
+
+	method := http.MethodPost
+	if op == OperationGet {
+		method = http.MethodGet
+	} else {
+		query.Set("value", value)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.cfg.Address+"/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.cfg.APIKey != "" {
+		req.Header.Set(auth.APIKeyHeader, r.cfg.APIKey)
+	}
+
+	return req, nil
+}
+
+// transportError shortens an error to a label for the report.
+func transportError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if urlErr.Timeout() {
+			return "timeout"
+		}
+		err = urlErr.Err
+	}
+
+	return fmt.Sprintf("%T", err)
+}
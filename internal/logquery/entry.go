@@ -0,0 +1,106 @@
+// Package logquery reads the JSON logs written by lib.CreateProductionLogger,
+// filters them and prints them readable, optionally grouped by trace.
+package logquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// timeLayout is the format of zapcore.ISO8601TimeEncoder.
+const timeLayout = "2006-01-02T15:04:05.000Z0700"
+
+// Entry is a single log line. The well-known keys of the encoder are moved out of Fields.
+type Entry struct {
+	Time      time.Time
+	Level     zapcore.Level
+	Component string
+	// Service is the name of the child logger, see lib.CreateChildLogger.
+	Service    string
+	Message    string
+	Caller     string
+	Stacktrace string
+	TraceID    string
+	Fields     map[string]any
+	// Raw is the line as read.
+	Raw string
+}
+
+// ParseEntry parses a JSON log line. Lines of zap.NewProduction with an epoch "ts" are understood as well.
+func ParseEntry(line []byte) (Entry, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return Entry{}, err
+	}
+	if _, ok := fields["msg"]; !ok {
+		return Entry{}, errors.New("not a log entry, msg is missing")
+	}
+
+	e := Entry{Raw: string(line)}
+
+	if level, ok := fields["level"].(string); ok {
+		if err := e.Level.UnmarshalText([]byte(level)); err != nil {
+			return Entry{}, fmt.Errorf("invalid level %q", level)
+		}
+	}
+
+	switch ts := fields["timestamp"].(type) {
+	case string:
+		t, err := time.Parse(timeLayout, ts)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+				return Entry{}, fmt.Errorf("invalid timestamp %q", ts)
+			}
+		}
+		e.Time = t
+	default:
+		if ts, ok := fields["ts"].(float64); ok {
+			sec, frac := math.Modf(ts)
+			e.Time = time.Unix(int64(sec), int64(frac*1e9))
+		}
+	}
+
+	e.Component = takeString(fields, "component")
+	e.Service = takeString(fields, "service")
+	e.Message = takeString(fields, "msg")
+	e.Caller = takeString(fields, "caller")
+	e.Stacktrace = takeString(fields, "stacktrace")
+	e.TraceID, _ = fields["trace_id"].(string)
+	delete(fields, "level")
+	delete(fields, "timestamp")
+	delete(fields, "ts")
+	e.Fields = fields
+
+	return e, nil
+}
+
+// Field returns the value of a key, including the well-known keys.
+func (e Entry) Field(key string) (any, bool) {
+	switch key {
+	case "level":
+		return e.Level.String(), true
+	case "component":
+		return e.Component, e.Component != ""
+	case "service":
+		return e.Service, e.Service != ""
+	case "msg":
+		return e.Message, true
+	case "caller":
+		return e.Caller, e.Caller != ""
+	}
+
+	value, ok := e.Fields[key]
+	return value, ok
+}
+
+func takeString(fields map[string]any, key string) string {
+	value, _ := fields[key].(string)
+	delete(fields, key)
+
+	return value
+}
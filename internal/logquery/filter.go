@@ -0,0 +1,147 @@
+package logquery
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Filter selects entries, the zero value selects everything.
+type Filter struct {
+	// MinLevel drops entries below the level, nil keeps all levels.
+	MinLevel *zapcore.Level
+	// Components match the component, the service or both as component/service, e.g. service-1/http-server.
+	Components []string
+	Since      time.Time
+	Until      time.Time
+	TraceID    string
+	// Exprs have to match all.
+	Exprs []Expr
+}
+
+func (f Filter) Match(e Entry) bool {
+	if f.MinLevel != nil && e.Level < *f.MinLevel {
+		return false
+	}
+	if len(f.Components) > 0 && !slices.ContainsFunc(f.Components, func(c string) bool {
+		return c == e.Component || c == e.Service || c == e.Component+"/"+e.Service
+	}) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.TraceID != "" && e.TraceID != f.TraceID {
+		return false
+	}
+	for _, expr := range f.Exprs {
+		if !expr.Match(e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ops are ordered so that the longer operators are tried first.
+var ops = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+// Expr compares a field of an entry with a value.
+type Expr struct {
+	Key   string
+	Op    string
+	Value string
+	re    *regexp.Regexp
+}
+
+// ParseExpr parses key=value, key!=value, key~regexp, key!~regexp and the numeric comparisons
+// key>n, key>=n, key<n and key<=n. Numbers may be durations, e.g. duration>10ms.
+func ParseExpr(s string) (Expr, error) {
+	i := strings.IndexAny(s, "=!~<>")
+	if i <= 0 {
+		return Expr{}, fmt.Errorf("invalid expression %q, expected key, operator and value", s)
+	}
+
+	expr := Expr{Key: s[:i]}
+	for _, op := range ops {
+		if strings.HasPrefix(s[i:], op) {
+			expr.Op = op
+			expr.Value = s[i+len(op):]
+			break
+		}
+	}
+
+	switch expr.Op {
+	case "":
+		return Expr{}, fmt.Errorf("invalid operator in expression %q", s)
+	case "~", "!~":
+		re, err := regexp.Compile(expr.Value)
+		if err != nil {
+			return Expr{}, fmt.Errorf("invalid regexp in expression %q: %w", s, err)
+		}
+		expr.re = re
+	case ">", ">=", "<", "<=":
+		if _, ok := number(expr.Value); !ok {
+			return Expr{}, fmt.Errorf("expression %q compares with %q, which is neither a number nor a duration", s, expr.Value)
+		}
+	}
+
+	return expr, nil
+}
+
+// Match reports whether the entry matches, a missing field only matches the negated operators.
+func (x Expr) Match(e Entry) bool {
+	value, ok := e.Field(x.Key)
+	if !ok {
+		return x.Op == "!=" || x.Op == "!~"
+	}
+	s := fmt.Sprint(value)
+
+	switch x.Op {
+	case "=":
+		return s == x.Value
+	case "!=":
+		return s != x.Value
+	case "~":
+		return x.re.MatchString(s)
+	case "!~":
+		return !x.re.MatchString(s)
+	}
+
+	got, ok := number(s)
+	if !ok {
+		return false
+	}
+	want, _ := number(x.Value)
+
+	switch x.Op {
+	case ">":
+		return got > want
+	case ">=":
+		return got >= want
+	case "<":
+		return got < want
+	default:
+		return got <= want
+	}
+}
+
+// number parses a float or a duration, durations are compared in seconds.
+func number(s string) (float64, bool) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d.Seconds(), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,197 @@
+package logquery
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const clockLayout = "15:04:05.000"
+
+var levelColors = map[zapcore.Level]string{
+	zapcore.DebugLevel: "\x1b[90m",
+	zapcore.InfoLevel:  "\x1b[34m",
+	zapcore.WarnLevel:  "\x1b[33m",
+	zapcore.ErrorLevel: "\x1b[31m",
+}
+
+const colorReset = "\x1b[0m"
+
+// Printer writes entries as one readable line each: time, level, component/service, message and the fields sorted by key.
+type Printer struct {
+	W      io.Writer
+	Color  bool
+	Caller bool
+	// Raw writes the entries as read, e.g. to pipe the result into another tool.
+	Raw bool
+}
+
+func (p *Printer) Print(e Entry) {
+	p.print(e, "", nil)
+}
+
+func (p *Printer) print(e Entry, indent string, omit []string) {
+	if p.Raw {
+		_, _ = fmt.Fprintln(p.W, e.Raw)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(indent)
+	b.WriteString(e.Time.Local().Format(clockLayout))
+	b.WriteByte(' ')
+
+	level := fmt.Sprintf("%-5s", e.Level.CapitalString())
+	if p.Color {
+		// DPanic, panic and fatal are colored like errors.
+		level = levelColors[min(e.Level, zapcore.ErrorLevel)] + level + colorReset
+	}
+	b.WriteString(level)
+	b.WriteByte(' ')
+
+	source := e.Component
+	if e.Service != "" {
+		source += "/" + e.Service
+	}
+	b.WriteString(source)
+	b.WriteString(": ")
+	b.WriteString(e.Message)
+
+	keys := make([]string, 0, len(e.Fields))
+	for key := range e.Fields {
+		if !slices.Contains(omit, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteByte(' ')
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(formatValue(e.Fields[key]))
+	}
+
+	if p.Caller && e.Caller != "" {
+		b.WriteString(" (")
+		b.WriteString(e.Caller)
+		b.WriteByte(')')
+	}
+
+	_, _ = fmt.Fprintln(p.W, b.String())
+
+	if e.Stacktrace != "" {
+		for _, line := range strings.Split(e.Stacktrace, "\n") {
+			_, _ = fmt.Fprintf(p.W, "%s    %s\n", indent, line)
+		}
+	}
+}
+
+// PrintTrace writes a header with the time span and components of the trace, followed by its entries.
+func (p *Printer) PrintTrace(t Trace) {
+	if p.Raw {
+		for _, e := range t.Entries {
+			p.print(e, "", nil)
+		}
+		return
+	}
+
+	if t.ID == "" {
+		_, _ = fmt.Fprintf(p.W, "without trace: %d lines\n", len(t.Entries))
+	} else {
+		_, _ = fmt.Fprintf(p.W, "trace %s: %s, %s, %s, %d lines\n",
+			t.ID,
+			t.Start().Local().Format(clockLayout),
+			t.Duration(),
+			strings.Join(t.Components(), ", "),
+			len(t.Entries),
+		)
+	}
+	for _, e := range t.Entries {
+		p.print(e, "  ", []string{"trace_id"})
+	}
+	_, _ = fmt.Fprintln(p.W)
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			return strconv.Quote(v)
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return "null"
+	default:
+		// Objects and arrays are printed as Go values, which is readable enough for a quick look.
+		return fmt.Sprint(v)
+	}
+}
+
+// Trace is the entries of one trace, which tells the story of a request across the services.
+type Trace struct {
+	// ID is empty for the entries which were not logged within a trace.
+	ID      string
+	Entries []Entry
+}
+
+func (t Trace) Start() time.Time {
+	return t.Entries[0].Time
+}
+
+func (t Trace) Duration() time.Duration {
+	return t.Entries[len(t.Entries)-1].Time.Sub(t.Start())
+}
+
+// Components returns the components which logged within the trace, sorted by name.
+func (t Trace) Components() []string {
+	var components []string
+	for _, e := range t.Entries {
+		if !slices.Contains(components, e.Component) {
+			components = append(components, e.Component)
+		}
+	}
+	sort.Strings(components)
+
+	return components
+}
+
+// Group collects the entries by trace ID, sorted by time. The traces are ordered by their first entry,
+// the entries without trace come last.
+func Group(entries []Entry) []Trace {
+	byID := make(map[string]*Trace)
+	var ids []string
+	for _, e := range entries {
+		t, ok := byID[e.TraceID]
+		if !ok {
+			t = &Trace{ID: e.TraceID}
+			byID[e.TraceID] = t
+			ids = append(ids, e.TraceID)
+		}
+		t.Entries = append(t.Entries, e)
+	}
+
+	traces := make([]Trace, 0, len(ids))
+	for _, id := range ids {
+		t := byID[id]
+		sort.SliceStable(t.Entries, func(i, j int) bool {
+			return t.Entries[i].Time.Before(t.Entries[j].Time)
+		})
+		traces = append(traces, *t)
+	}
+	sort.SliceStable(traces, func(i, j int) bool {
+		if traces[i].ID == "" || traces[j].ID == "" {
+			return traces[j].ID == "" && traces[i].ID != ""
+		}
+		return traces[i].Start().Before(traces[j].Start())
+	})
+
+	return traces
+}
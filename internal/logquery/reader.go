@@ -0,0 +1,110 @@
+package logquery
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// maxLineSize is large enough for entries with a stacktrace.
+const maxLineSize = 1024 * 1024
+
+// pollInterval is how often Follow checks the file for new lines.
+const pollInterval = 250 * time.Millisecond
+
+// Read calls fn for every entry of r, lines which are not JSON log entries are counted and skipped.
+func Read(r io.Reader, fn func(Entry)) (skipped int, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	for scanner.Scan() {
+		e, err := ParseEntry(scanner.Bytes())
+		if err != nil {
+			skipped++
+			continue
+		}
+		fn(e)
+	}
+
+	return skipped, scanner.Err()
+}
+
+// Follow calls fn for every entry appended to the file at path until ctx is done, like tail -f.
+// The existing entries are read first. A truncated or rotated file is read again from the start.
+func Follow(ctx context.Context, path string, fn func(Entry)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	reader := bufio.NewReaderSize(f, 64*1024)
+	var offset int64
+	var partial []byte
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		offset += int64(len(line))
+		if err == nil {
+			line = append(partial, line...)
+			partial = nil
+			if e, err := ParseEntry(line); err == nil {
+				fn(e)
+			}
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		// The writer may not have finished the line yet.
+		partial = append(partial, line...)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		reopened, err := reopen(path, f, offset)
+		if err != nil {
+			return err
+		}
+		if reopened != nil {
+			_ = f.Close()
+			f = reopened
+			reader.Reset(f)
+			offset = 0
+			partial = nil
+		}
+	}
+}
+
+// reopen opens path again if the file was replaced or truncated, otherwise it returns nil.
+func reopen(path string, f *os.File, offset int64) (*os.File, error) {
+	current, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// The file is being rotated, try again at the next poll.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	opened, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if os.SameFile(current, opened) && current.Size() >= offset {
+		return nil, nil
+	}
+
+	return os.Open(path)
+}
@@ -0,0 +1,222 @@
+package service1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"observability-demo/lib"
+	"observability-demo/lib/health"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type StoreClient struct {
+	baseURL string
+	client  *http.Client
+
+	log    *zap.SugaredLogger
+	tracer trace.Tracer
+}
+
+// NewClient talks to service-2 via HTTP, transport should be instrumented with otelhttp.
+func NewClient(baseURL string, transport http.RoundTripper, tracer trace.Tracer, log *zap.SugaredLogger) Client {
+	return &StoreClient{
+		baseURL: baseURL,
+		client:  &http.Client{Transport: transport},
+		tracer:  tracer,
+		log:     log,
+	}
+}
+
+func (s *StoreClient) Get(ctx context.Context, key string) (string, error) {
+	ctx, span := s.tracer.Start(ctx, "in-client-get", trace.WithAttributes(lib.KeyKey.String(key)))
+	defer span.End()
+
+	value, err := s.get(ctx, key)
+	if err != nil {
+		lib.RecordError(span, err)
+		return "", err
+	}
+	span.SetAttributes(lib.KeyFoundKey.Bool(true))
+
+	return value, nil
+}
+
+func (s *StoreClient) get(ctx context.Context, key string) (string, error) {
+	query := url.Values{}
+	query.Set("key", key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/get?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	lib.SetRequestID(ctx, req.Header)
+	lib.SetTenant(ctx, req.Header)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	// The client span of the HTTP instrumentation only ends once the body is closed.
+	log := lib.ContextLogger(ctx, s.log)
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			log.Errorf("failed to close response body: %v", err)
+		}
+	}()
+
+	if err := statusError(resp); err != nil {
+		return "", fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	var result lib.Result
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("failed to read response body: %v", err)
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		log.Errorf("failed to unmarshal response body: %v", err)
+
+		return "", fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	log.Debugw("got value", "key", key, "value", result.Value)
+
+	return result.Value, nil
+}
+
+func (s *StoreClient) Set(ctx context.Context, key, value string) error {
+	ctx, span := s.tracer.Start(ctx, "in-client-set", trace.WithAttributes(lib.KeyKey.String(key)))
+	defer span.End()
+
+	if err := s.set(ctx, key, value); err != nil {
+		lib.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *StoreClient) set(ctx context.Context, key, value string) error {
+	query := url.Values{}
+	query.Set("key", key)
+	query.Set("value", value)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/set?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	lib.SetRequestID(ctx, req.Header)
+	lib.SetTenant(ctx, req.Header)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if err := statusError(resp); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *StoreClient) ListNamespaces(ctx context.Context) ([]lib.Namespace, error) {
+	ctx, span := s.tracer.Start(ctx, "in-client-list-namespaces")
+	defer span.End()
+
+	namespaces := make([]lib.Namespace, 0)
+	if err := s.do(ctx, http.MethodGet, "/namespaces", &namespaces); err != nil {
+		err = fmt.Errorf("failed to list namespaces: %w", err)
+		lib.RecordError(span, err)
+		return nil, err
+	}
+
+	return namespaces, nil
+}
+
+func (s *StoreClient) DeleteNamespace(ctx context.Context, name string) (int, error) {
+	ctx, span := s.tracer.Start(ctx, "in-client-delete-namespace")
+	defer span.End()
+
+	var deleted lib.DeletedNamespace
+	if err := s.do(ctx, http.MethodDelete, "/namespaces/"+url.PathEscape(name), &deleted); err != nil {
+		err = fmt.Errorf("failed to delete namespace %s: %w", name, err)
+		lib.RecordError(span, err)
+		return 0, err
+	}
+
+	return deleted.DeletedKeys, nil
+}
+
+// do sends a request to service-2 and unmarshals the JSON response into result.
+func (s *StoreClient) do(ctx context.Context, method, path string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	lib.SetRequestID(ctx, req.Header)
+	lib.SetTenant(ctx, req.Header)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if err := statusError(resp); err != nil {
+		return err
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// statusError converts the status of a response of service-2 back into the error of the store.
+func statusError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return lib.ErrNotFound
+	case http.StatusForbidden:
+		return lib.ErrUnknownTenant
+	case http.StatusTooManyRequests:
+		return lib.ErrQuotaExceeded
+	default:
+		return errors.New(resp.Status)
+	}
+}
+
+func (s *StoreClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+health.ReadyzPath, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("service-2 is not ready: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package service1
+
+import (
+	"fmt"
+	"observability-demo/lib"
+	"observability-demo/lib/auth"
+	"observability-demo/lib/ratelimit"
+	"strconv"
+)
+
+type StoreTransport string
+
+const (
+	TransportHTTP StoreTransport = "http"
+	TransportGRPC StoreTransport = "grpc"
+)
+
+type Config struct {
+	// StoreTransport selects how service-1 talks to service-2.
+	StoreTransport   StoreTransport
+	StoreHTTPAddress string
+	StoreGRPCAddress string
+	// FaultInjection serves the admin endpoint to inject faults into the API, it requires AdminToken.
+	FaultInjection bool
+	// AdminToken protects the admin endpoints, they are disabled without it.
+	AdminToken string
+	// AdminAddress is the address of the listener with the debug endpoints, it's disabled if empty.
+	AdminAddress string
+	// Tenants are allowed to use the API, empty allows every tenant.
+	Tenants []string
+	// Auth configures the authentication of the callers, the API is open if no method is configured.
+	Auth auth.Config
+	// RateLimit limits the requests of every client, it's disabled with a rate of 0.
+	RateLimit ratelimit.Config
+	// StoreConcurrency limits the concurrent calls to service-2, it's disabled with a maximum of 0.
+	StoreConcurrency ratelimit.ConcurrencyConfig
+}
+
+// LoadConfig reads the configuration from the environment.
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		StoreTransport:   StoreTransport(lib.GetEnv("STORE_TRANSPORT", string(TransportHTTP))),
+		StoreHTTPAddress: lib.GetEnv("STORE_HTTP_ADDRESS", "http://localhost:4041"),
+		StoreGRPCAddress: lib.GetEnv("STORE_GRPC_ADDRESS", "localhost:4042"),
+		AdminToken:       lib.GetEnv("ADMIN_TOKEN", ""),
+		AdminAddress:     lib.GetEnv("ADMIN_ADDRESS", ""),
+		Tenants:          lib.SplitList(lib.GetEnv("TENANTS", "")),
+	}
+
+	var err error
+	cfg.FaultInjection, err = strconv.ParseBool(lib.GetEnv("FAULT_INJECTION", "false"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid FAULT_INJECTION: %w", err)
+	}
+
+	cfg.Auth, err = auth.LoadConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.RateLimit, err = ratelimit.LoadConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.RateLimit.Tenants = cfg.Tenants
+	cfg.StoreConcurrency, err = ratelimit.LoadConcurrencyConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
+	switch cfg.StoreTransport {
+	case TransportHTTP, TransportGRPC:
+	default:
+		return Config{}, fmt.Errorf("unknown store transport %q", cfg.StoreTransport)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,149 @@
+package service1
+
+import (
+	"context"
+	"fmt"
+	"observability-demo/api/kv"
+	"observability-demo/lib"
+	"observability-demo/lib/auth"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCStoreClient talks to service-2 via the KV gRPC API.
+type GRPCStoreClient struct {
+	client kv.KVClient
+	health healthpb.HealthClient
+
+	log    *zap.SugaredLogger
+	tracer trace.Tracer
+}
+
+// DialStore opens an instrumented gRPC connection to service-2, the calls carry the token of serviceAuth.
+func DialStore(address string, tp trace.TracerProvider, serviceAuth *auth.ServiceAuth) (*grpc.ClientConn, error) {
+	return grpc.NewClient(
+		address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithTracerProvider(tp))),
+		grpc.WithUnaryInterceptor(serviceAuth.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(serviceAuth.StreamClientInterceptor()),
+	)
+}
+
+func NewGRPCClient(conn grpc.ClientConnInterface, tracer trace.Tracer, log *zap.SugaredLogger) Client {
+	return &GRPCStoreClient{
+		client: kv.NewKVClient(conn),
+		health: healthpb.NewHealthClient(conn),
+		tracer: tracer,
+		log:    log,
+	}
+}
+
+func (s *GRPCStoreClient) Get(ctx context.Context, key string) (string, error) {
+	ctx, span := s.tracer.Start(ctx, "in-client-get", trace.WithAttributes(lib.KeyKey.String(key)))
+	defer span.End()
+
+	resp, err := s.client.Get(outgoing(ctx), &kv.GetRequest{Key: key})
+	if err != nil {
+		err = fmt.Errorf("failed to get key %s: %w", key, fromStatus(err))
+		lib.RecordError(span, err)
+		return "", err
+	}
+	span.SetAttributes(lib.KeyFoundKey.Bool(true))
+
+	lib.ContextLogger(ctx, s.log).Debugw("got value", "key", key, "value", resp.GetValue())
+
+	return resp.GetValue(), nil
+}
+
+func (s *GRPCStoreClient) Set(ctx context.Context, key, value string) error {
+	ctx, span := s.tracer.Start(ctx, "in-client-set", trace.WithAttributes(lib.KeyKey.String(key)))
+	defer span.End()
+
+	_, err := s.client.Set(outgoing(ctx), &kv.SetRequest{Key: key, Value: value})
+	if err != nil {
+		err = fmt.Errorf("failed to set key %s: %w", key, fromStatus(err))
+		lib.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *GRPCStoreClient) ListNamespaces(ctx context.Context) ([]lib.Namespace, error) {
+	ctx, span := s.tracer.Start(ctx, "in-client-list-namespaces")
+	defer span.End()
+
+	resp, err := s.client.ListNamespaces(outgoing(ctx), &kv.ListNamespacesRequest{})
+	if err != nil {
+		err = fmt.Errorf("failed to list namespaces: %w", fromStatus(err))
+		lib.RecordError(span, err)
+		return nil, err
+	}
+
+	namespaces := make([]lib.Namespace, 0, len(resp.GetNamespaces()))
+	for _, ns := range resp.GetNamespaces() {
+		namespaces = append(namespaces, lib.Namespace{
+			Name:     ns.GetName(),
+			Keys:     int(ns.GetKeys()),
+			Bytes:    int(ns.GetBytes()),
+			MaxKeys:  int(ns.GetMaxKeys()),
+			MaxBytes: int(ns.GetMaxBytes()),
+		})
+	}
+
+	return namespaces, nil
+}
+
+func (s *GRPCStoreClient) DeleteNamespace(ctx context.Context, name string) (int, error) {
+	ctx, span := s.tracer.Start(ctx, "in-client-delete-namespace")
+	defer span.End()
+
+	resp, err := s.client.DeleteNamespace(outgoing(ctx), &kv.DeleteNamespaceRequest{Namespace: name})
+	if err != nil {
+		err = fmt.Errorf("failed to delete namespace %s: %w", name, fromStatus(err))
+		lib.RecordError(span, err)
+		return 0, err
+	}
+
+	return int(resp.GetDeletedKeys()), nil
+}
+
+func (s *GRPCStoreClient) Ping(ctx context.Context) error {
+	resp, err := s.health.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("service-2 is not ready: %s", resp.GetStatus())
+	}
+
+	return nil
+}
+
+// outgoing adds the request ID and the tenant of the current request to the metadata of a call.
+func outgoing(ctx context.Context) context.Context {
+	return lib.OutgoingTenant(lib.OutgoingRequestID(ctx))
+}
+
+// fromStatus converts the status of a call back into the error of the store.
+func fromStatus(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return lib.ErrNotFound
+	case codes.PermissionDenied:
+		return lib.ErrUnknownTenant
+	case codes.ResourceExhausted:
+		return lib.ErrQuotaExceeded
+	default:
+		return err
+	}
+}
@@ -0,0 +1,261 @@
+package service1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"observability-demo/lib"
+	"observability-demo/lib/auth"
+	"observability-demo/lib/profiling"
+	"observability-demo/lib/ratelimit"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type Client interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+	// Ping checks that service-2 is reachable and ready.
+	Ping(ctx context.Context) error
+	ListNamespaces(ctx context.Context) ([]lib.Namespace, error)
+	DeleteNamespace(ctx context.Context, name string) (int, error)
+}
+
+type Controller struct {
+	client  Client
+	tenants []string
+	authn   *auth.Authenticator
+
+	tracer trace.Tracer
+	log    *zap.SugaredLogger
+}
+
+// NewController serves the API to the tenants, an empty list allows every tenant.
+// The operations are authorized by the ACL of authn, a nil authn allows everything.
+func NewController(store Client, tenants []string, authn *auth.Authenticator, tracer trace.Tracer, log *zap.SugaredLogger) *Controller {
+	return &Controller{
+		client:  store,
+		tenants: tenants,
+		authn:   authn,
+		tracer:  tracer,
+		log:     log,
+	}
+}
+
+func (c *Controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(r.Context(), "in-controller-entry", trace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLPath(r.URL.Path),
+	))
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, r.Pattern)
+	defer unlabel()
+
+	ctx, ok := c.withTenant(ctx, w, r, span)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		c.handleGet(ctx, w, r)
+	case "POST":
+		c.handlePost(ctx, w, r)
+	default:
+		lib.HTTPError(w, span, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (c *Controller) handleGet(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(ctx, "in-handle-get")
+	defer span.End()
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		lib.HTTPError(w, span, http.StatusBadRequest, errors.New("missing key"))
+		return
+	}
+	span.SetAttributes(lib.KeyKey.String(key))
+	if !c.authorize(ctx, w, span, auth.ActionRead, key) {
+		return
+	}
+
+	value, err := c.client.Get(ctx, key)
+	if errors.Is(err, ratelimit.ErrLimited) {
+		shed(w, span, err)
+		return
+	}
+	if errors.Is(err, lib.ErrNotFound) || errors.Is(err, lib.ErrUnknownTenant) {
+		lib.HTTPError(w, span, lib.HTTPStatus(err), err)
+		return
+	}
+	if err != nil {
+		lib.ContextLogger(ctx, c.log).Errorw("failed to get value", "key", key, "error", err)
+		lib.HTTPError(w, span, http.StatusInternalServerError, err)
+		return
+	}
+	span.SetAttributes(lib.KeyFoundKey.Bool(true))
+
+	_, err = w.Write([]byte(value))
+	if err != nil {
+		lib.ContextLogger(ctx, c.log).Errorw("failed to write response", "error", err)
+		lib.RecordError(span, err)
+		return
+	}
+}
+
+func (c *Controller) handlePost(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(ctx, "in-handle-post")
+	defer span.End()
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		lib.HTTPError(w, span, http.StatusBadRequest, errors.New("missing key"))
+		return
+	}
+	span.SetAttributes(lib.KeyKey.String(key))
+
+	value := r.URL.Query().Get("value")
+	if value == "" {
+		lib.HTTPError(w, span, http.StatusBadRequest, errors.New("missing value"))
+		return
+	}
+
+	if !c.authorize(ctx, w, span, auth.ActionWrite, key) {
+		return
+	}
+
+	err := c.client.Set(ctx, key, value)
+	if errors.Is(err, ratelimit.ErrLimited) {
+		shed(w, span, err)
+		return
+	}
+	if errors.Is(err, lib.ErrQuotaExceeded) || errors.Is(err, lib.ErrUnknownTenant) {
+		lib.HTTPError(w, span, lib.HTTPStatus(err), err)
+		return
+	}
+	if err != nil {
+		lib.ContextLogger(ctx, c.log).Errorw("failed to set value", "key", key, "error", err)
+		lib.HTTPError(w, span, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListNamespaces serves the usage and quota of the namespace of the tenant.
+func (c *Controller) ListNamespaces(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(r.Context(), "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, r.Pattern)
+	defer unlabel()
+
+	ctx, ok := c.withTenant(ctx, w, r, span)
+	if !ok || !c.authorize(ctx, w, span, auth.ActionRead, "") {
+		return
+	}
+
+	namespaces, err := c.client.ListNamespaces(ctx)
+	if errors.Is(err, ratelimit.ErrLimited) {
+		shed(w, span, err)
+		return
+	}
+	if err != nil {
+		lib.ContextLogger(ctx, c.log).Errorw("failed to list namespaces", "error", err)
+		lib.HTTPError(w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.writeJSON(ctx, w, span, namespaces)
+}
+
+// DeleteNamespace deletes all keys of a namespace, tenants can only delete their own namespace.
+func (c *Controller) DeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(r.Context(), "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, r.Pattern)
+	defer unlabel()
+
+	ctx, ok := c.withTenant(ctx, w, r, span)
+	if !ok {
+		return
+	}
+
+	name := r.PathValue("namespace")
+	if name != lib.Tenant(ctx) {
+		lib.HTTPError(w, span, http.StatusForbidden, fmt.Errorf("tenant %s can't delete namespace %s", lib.Tenant(ctx), name))
+		return
+	}
+	if !c.authorize(ctx, w, span, auth.ActionDelete, "") {
+		return
+	}
+
+	deleted, err := c.client.DeleteNamespace(ctx, name)
+	if errors.Is(err, ratelimit.ErrLimited) {
+		shed(w, span, err)
+		return
+	}
+	if err != nil {
+		lib.ContextLogger(ctx, c.log).Errorw("failed to delete namespace", "namespace", name, "error", err)
+		lib.HTTPError(w, span, lib.HTTPStatus(err), err)
+		return
+	}
+
+	c.writeJSON(ctx, w, span, lib.DeletedNamespace{Name: name, DeletedKeys: deleted})
+}
+
+// withTenant resolves the tenant of the request and stores it in ctx, so the client forwards it to service-2.
+// It writes the error response and returns false if the tenant is not valid or not allowed.
+func (c *Controller) withTenant(ctx context.Context, w http.ResponseWriter, r *http.Request, span trace.Span) (context.Context, bool) {
+	tenant, err := lib.ResolveTenant(r, c.tenants)
+	if err != nil {
+		lib.HTTPError(w, span, lib.HTTPStatus(err), err)
+		return ctx, false
+	}
+	span.SetAttributes(lib.TenantKey.String(tenant))
+
+	return lib.WithTenant(ctx, tenant), true
+}
+
+// authorize writes a 403 response and returns false if the caller may not perform action on key,
+// an empty key stands for the whole namespace.
+func (c *Controller) authorize(ctx context.Context, w http.ResponseWriter, span trace.Span, action, key string) bool {
+	if err := c.authn.Authorize(ctx, action, key); err != nil {
+		lib.HTTPError(w, span, http.StatusForbidden, err)
+		return false
+	}
+
+	return true
+}
+
+// shed answers a request whose call to service-2 was rejected by the concurrency limiter with 429 like
+// a rate limited request, the client may retry after a second. The span still fails, the overload is on our side.
+func shed(w http.ResponseWriter, span trace.Span, err error) {
+	span.SetAttributes(semconv.ErrorTypeKey.String("concurrency_limited"))
+	ratelimit.SetRetryAfter(w, time.Second)
+	span.SetStatus(codes.Error, err.Error())
+	http.Error(w, "service-2 is overloaded, retry later", http.StatusTooManyRequests)
+}
+
+func (c *Controller) writeJSON(ctx context.Context, w http.ResponseWriter, span trace.Span, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		lib.ContextLogger(ctx, c.log).Errorw("failed to marshal response", "error", err)
+		lib.HTTPError(w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		lib.ContextLogger(ctx, c.log).Errorw("failed to write response", "error", err)
+		lib.RecordError(span, err)
+	}
+}
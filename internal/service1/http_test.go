@@ -0,0 +1,176 @@
+package service1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"observability-demo/lib"
+	"observability-demo/lib/ratelimit"
+	"observability-demo/lib/telemetrytest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.uber.org/zap"
+)
+
+// newTestController serves the controller with a StoreClient which talks to store instead of service-2.
+func newTestController(t *testing.T, store http.HandlerFunc) (http.Handler, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	tp, spans := telemetrytest.NewTracerProvider(t)
+	backend := httptest.NewServer(store)
+	t.Cleanup(backend.Close)
+
+	log := zap.NewNop().Sugar()
+	client := NewClient(backend.URL, http.DefaultTransport, tp.Tracer("client"), log)
+
+	return NewController(client, nil, nil, tp.Tracer("controller"), log), spans
+}
+
+func serve(t *testing.T, h http.Handler, method, target, tenant string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, nil)
+	if tenant != "" {
+		req.Header.Set(lib.TenantHeader, tenant)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestControllerGetSpans(t *testing.T) {
+	var gotTenant string
+	controller, spans := newTestController(t, func(w http.ResponseWriter, r *http.Request) {
+		gotTenant = r.Header.Get(lib.TenantHeader)
+		_ = json.NewEncoder(w).Encode(lib.Result{Key: r.URL.Query().Get("key"), Value: "bar"})
+	})
+
+	rec := serve(t, controller, http.MethodGet, "/?key=foo", "acme")
+	if rec.Code != http.StatusOK || rec.Body.String() != "bar" {
+		t.Fatalf("expected 200 bar, got %d %q", rec.Code, rec.Body.String())
+	}
+	if gotTenant != "acme" {
+		t.Fatalf("expected the tenant acme to be forwarded, got %q", gotTenant)
+	}
+
+	telemetrytest.AssertTree(t, spans, telemetrytest.Tree{
+		Name: "in-controller-entry",
+		Children: []telemetrytest.Tree{{
+			Name:     "in-handle-get",
+			Children: []telemetrytest.Tree{{Name: "in-client-get"}},
+		}},
+	})
+	telemetrytest.AssertNotError(t, telemetrytest.AssertSpan(t, spans, "in-controller-entry", lib.TenantKey.String("acme")))
+	telemetrytest.AssertNotError(t, telemetrytest.AssertSpan(t, spans, "in-handle-get", lib.KeyKey.String("foo"), lib.KeyFoundKey.Bool(true)))
+	telemetrytest.AssertNotError(t, telemetrytest.AssertSpan(t, spans, "in-client-get", lib.KeyKey.String("foo"), lib.KeyFoundKey.Bool(true)))
+}
+
+func TestControllerSetSpans(t *testing.T) {
+	controller, spans := newTestController(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Query().Get("value") != "bar" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	})
+
+	rec := serve(t, controller, http.MethodPost, "/?key=foo&value=bar", "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	telemetrytest.AssertTree(t, spans, telemetrytest.Tree{
+		Name: "in-controller-entry",
+		Children: []telemetrytest.Tree{{
+			Name:     "in-handle-post",
+			Children: []telemetrytest.Tree{{Name: "in-client-set"}},
+		}},
+	})
+	telemetrytest.AssertSpan(t, spans, "in-controller-entry", lib.TenantKey.String(lib.DefaultTenant))
+	telemetrytest.AssertNotError(t, telemetrytest.AssertSpan(t, spans, "in-handle-post", lib.KeyKey.String("foo")))
+	telemetrytest.AssertNotError(t, telemetrytest.AssertSpan(t, spans, "in-client-set", lib.KeyKey.String("foo")))
+}
+
+func TestControllerMissingKey(t *testing.T) {
+	controller, spans := newTestController(t, func(http.ResponseWriter, *http.Request) {
+		t.Error("service-2 must not be called without key")
+	})
+
+	rec := serve(t, controller, http.MethodGet, "/", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+
+	// The mistake of the caller doesn't fail the server span, it's only recorded as error.type.
+	telemetrytest.AssertNotError(t, telemetrytest.AssertSpan(t, spans, "in-handle-get", semconv.ErrorTypeKey.String("400")))
+	telemetrytest.AssertNotError(t, telemetrytest.AssertSpan(t, spans, "in-controller-entry"))
+	telemetrytest.AssertNoSpan(t, spans, "in-client-get")
+}
+
+func TestClientNotFound(t *testing.T) {
+	controller, spans := newTestController(t, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "key not found", http.StatusNotFound)
+	})
+
+	rec := serve(t, controller, http.MethodGet, "/?key=foo", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+
+	// A miss is an expected outcome, neither the client nor the controller span fails.
+	for _, name := range []string{"in-client-get", "in-handle-get"} {
+		span := telemetrytest.AssertSpan(t, spans, name, lib.KeyKey.String("foo"), lib.KeyFoundKey.Bool(false))
+		telemetrytest.AssertNotError(t, span)
+	}
+}
+
+func TestClientServerError(t *testing.T) {
+	controller, spans := newTestController(t, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	})
+
+	rec := serve(t, controller, http.MethodGet, "/?key=foo", "")
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+
+	client := telemetrytest.AssertSpan(t, spans, "in-client-get", lib.KeyKey.String("foo"))
+	telemetrytest.AssertError(t, client, "500 Internal Server Error")
+	telemetrytest.AssertException(t, client, "failed to get key foo")
+
+	handle := telemetrytest.AssertSpan(t, spans, "in-handle-get", semconv.ErrorTypeKey.String("500"))
+	telemetrytest.AssertError(t, handle, "500 Internal Server Error")
+	telemetrytest.AssertException(t, handle, "failed to get key foo")
+}
+
+func TestControllerShedsWithRetryAfter(t *testing.T) {
+	tp, spans := telemetrytest.NewTracerProvider(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("service-2 must not be called above the concurrency limit")
+	}))
+	t.Cleanup(backend.Close)
+
+	log := zap.NewNop().Sugar()
+	limiter := ratelimit.NewConcurrencyLimiter("service-2", ratelimit.ConcurrencyConfig{MaxLimit: 1, MinLimit: 1}, prometheus.NewRegistry())
+	client := NewLimitedClient(NewClient(backend.URL, http.DefaultTransport, tp.Tracer("client"), log), limiter)
+	controller := NewController(client, nil, nil, tp.Tracer("controller"), log)
+
+	// The only slot is taken by another call.
+	release, err := limiter.Acquire(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { release(false) })
+
+	rec := serve(t, controller, http.MethodGet, "/?key=foo", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected Retry-After 1, got %q", rec.Header().Get("Retry-After"))
+	}
+	span := telemetrytest.AssertSpan(t, spans, "in-handle-get", semconv.ErrorTypeKey.String("concurrency_limited"))
+	telemetrytest.AssertError(t, span, ratelimit.ErrLimited.Error())
+}
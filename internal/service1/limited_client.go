@@ -0,0 +1,81 @@
+package service1
+
+import (
+	"context"
+	"errors"
+	"observability-demo/lib"
+	"observability-demo/lib/ratelimit"
+)
+
+// limitedClient limits the concurrent calls to service-2, so a burst of requests is shed by service-1
+// instead of piling up in service-2. Ping is not limited, so the health check still sees service-2.
+// The slots are released with defer, so a panic in the wrapped client doesn't leak one.
+type limitedClient struct {
+	Client
+	limiter *ratelimit.ConcurrencyLimiter
+}
+
+// NewLimitedClient returns client itself if limiter is nil.
+func NewLimitedClient(client Client, limiter *ratelimit.ConcurrencyLimiter) Client {
+	if limiter == nil {
+		return client
+	}
+
+	return &limitedClient{Client: client, limiter: limiter}
+}
+
+func (c *limitedClient) Get(ctx context.Context, key string) (value string, err error) {
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() { release(overloaded(err)) }()
+
+	return c.Client.Get(ctx, key)
+}
+
+func (c *limitedClient) Set(ctx context.Context, key, value string) (err error) {
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { release(overloaded(err)) }()
+
+	return c.Client.Set(ctx, key, value)
+}
+
+func (c *limitedClient) ListNamespaces(ctx context.Context) (namespaces []lib.Namespace, err error) {
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { release(overloaded(err)) }()
+
+	return c.Client.ListNamespaces(ctx)
+}
+
+func (c *limitedClient) DeleteNamespace(ctx context.Context, name string) (deleted int, err error) {
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { release(overloaded(err)) }()
+
+	return c.Client.DeleteNamespace(ctx, name)
+}
+
+// overloaded tells whether err hints at an overloaded service-2. The errors of the store are regular answers
+// and a canceled request says nothing about service-2.
+func overloaded(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, lib.ErrNotFound),
+		errors.Is(err, lib.ErrQuotaExceeded),
+		errors.Is(err, lib.ErrUnknownTenant),
+		errors.Is(err, lib.ErrInvalidTenant),
+		errors.Is(err, context.Canceled):
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,146 @@
+package service1
+
+import (
+	"fmt"
+	"net/http"
+	"observability-demo/lib"
+	"observability-demo/lib/auth"
+	"observability-demo/lib/fault"
+	"observability-demo/lib/health"
+	"observability-demo/lib/ratelimit"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// NewServer serves the API, metrics and health checks.
+// If faults is not nil, its rules apply to the API. admin serves the endpoints below /admin/.
+// Every API request is written to the access log on log. baggage may be nil.
+// If authn is not nil, the API rejects requests without valid credentials.
+// If limiter is not nil, the API rejects the requests of clients which exceed the rate limit.
+// It returns the handler and the patterns it serves.
+func NewServer(controller *Controller, tp trace.TracerProvider, reg *prometheus.Registry, checks *health.Health, faults *fault.Injector, admin http.Handler, log *zap.SugaredLogger, baggage *lib.Baggage, authn *auth.Authenticator, limiter *ratelimit.Limiter) (http.Handler, []string) {
+	mux := http.NewServeMux()
+	var routes []string
+
+	// handleFunc is a replacement for mux.HandleFunc
+	// which enriches the handler's HTTP instrumentation with the pattern as the http.route.
+	handleFunc := func(pattern string, handlerFunc func(http.ResponseWriter, *http.Request)) {
+		var handler http.Handler = http.HandlerFunc(handlerFunc)
+		if faults != nil {
+			handler = faults.Middleware(handler)
+		}
+		// The clients are limited after the authentication, so they can be limited by API key.
+		handler = limiter.Middleware(handler)
+		handler = authn.Middleware(handler)
+		// Requests which fail the authentication are limited by IP, they never reach the limit by API key.
+		handler = limiter.Unauthenticated(handler)
+		handler = lib.RequestLogging(log, handler)
+		// Configure the "http.route" for the HTTP instrumentation.
+		handler = otelhttp.WithRouteTag(pattern, handler)
+		mux.Handle(pattern, handler)
+		routes = append(routes, pattern)
+	}
+
+	handleFunc("/", controller.ServeHTTP)
+	handleFunc("GET /namespaces", controller.ListNamespaces)
+	handleFunc("DELETE /namespaces/{namespace}", controller.DeleteNamespace)
+	mux.Handle(lib.MetricsPath, lib.MetricsHandler(reg))
+	checks.Handle(mux)
+	mux.Handle(lib.AdminPrefix, admin)
+	routes = append(routes, lib.MetricsPath, health.LivezPath, health.ReadyzPath, health.HealthzPath, lib.AdminPrefix)
+
+	metrics := lib.NewHTTPMetrics(reg, baggage)
+
+	// Add HTTP instrumentation for the whole server.
+	handler := otelhttp.NewHandler(
+		baggage.Middleware(metrics.Middleware(lib.WithTraceResponse(mux))),
+		"/",
+		otelhttp.WithTracerProvider(tp),
+		otelhttp.WithFilter(lib.TracedRequest),
+	)
+	return handler, routes
+}
+
+// Service is service-1 wired up, ready to be served.
+type Service struct {
+	Handler http.Handler
+	// Routes are the patterns served by Handler.
+	Routes []string
+	Health *health.Health
+	// Faults is nil unless fault injection is enabled.
+	Faults *fault.Injector
+
+	conn *grpc.ClientConn
+}
+
+func New(cfg Config, tel lib.Telemetry) (*Service, error) {
+	logs := tel.Logger.Sugar()
+	httpSrvLogger := lib.CreateChildLogger(tel.Logger, "http-server")
+	clientLogger := lib.CreateChildLogger(tel.Logger, "client")
+	storeTracer := tel.TracerProvider.Tracer("store")
+
+	svc := &Service{}
+
+	auditLogger := lib.CreateChildLogger(tel.Logger, "audit")
+	var authn *auth.Authenticator
+	if cfg.Auth.Enabled() {
+		var err error
+		authn, err = auth.New(cfg.Auth, tel.Registry, auditLogger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up authentication: %w", err)
+		}
+	} else {
+		logs.Warn("no authentication method is configured, the API is open to everyone")
+	}
+	serviceAuth := auth.NewServiceAuth(cfg.Auth.ServiceToken, tel.Registry, auditLogger)
+
+	var store Client
+	switch cfg.StoreTransport {
+	case TransportGRPC:
+		conn, err := DialStore(cfg.StoreGRPCAddress, tel.TracerProvider, serviceAuth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial store: %w", err)
+		}
+		svc.conn = conn
+		store = NewGRPCClient(conn, storeTracer, clientLogger)
+	default:
+		transport := serviceAuth.Transport(otelhttp.NewTransport(http.DefaultTransport, otelhttp.WithTracerProvider(tel.TracerProvider)))
+		store = NewClient(cfg.StoreHTTPAddress, transport, storeTracer, clientLogger)
+	}
+	logs.Infof("using %s transport for the store", cfg.StoreTransport)
+	store = NewLimitedClient(store, ratelimit.NewConcurrencyLimiter("service-2", cfg.StoreConcurrency, tel.Registry))
+
+	controller := NewController(store, cfg.Tenants, authn, tel.TracerProvider.Tracer("controller"), httpSrvLogger)
+
+	svc.Health = health.New()
+	svc.Health.Register("service-2", store.Ping)
+	svc.Health.Register("trace-exporter", lib.CheckTraceExporter, health.Informational())
+	tel.Registry.MustRegister(svc.Health)
+
+	admin := http.NewServeMux()
+	if levels := lib.LogLevelsOf(tel.Logger); levels != nil {
+		levels.Handle(admin, cfg.AdminToken)
+	}
+	if cfg.FaultInjection {
+		svc.Faults = fault.NewInjector(tel.Registry, lib.CreateChildLogger(tel.Logger, "fault-injection"))
+		svc.Faults.Handle(admin, cfg.AdminToken)
+	}
+
+	svc.Handler, svc.Routes = NewServer(controller, tel.TracerProvider, tel.Registry, svc.Health, svc.Faults, admin, httpSrvLogger, tel.Baggage, authn,
+		ratelimit.NewLimiter(cfg.RateLimit, tel.Registry, lib.CreateChildLogger(tel.Logger, "rate-limit")))
+
+	return svc, nil
+}
+
+// Close closes the connection to service-2.
+func (s *Service) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	return s.conn.Close()
+}
@@ -0,0 +1,103 @@
+package service2
+
+import (
+	"fmt"
+	"observability-demo/lib"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Store StoreConfig
+	// Tenants are allowed to use the store, empty allows every tenant.
+	Tenants []string
+	// FaultInjection serves the admin endpoint to inject faults into the API, it requires AdminToken.
+	FaultInjection bool
+	// AdminToken protects the admin endpoints, they are disabled without it.
+	AdminToken string
+	// AdminAddress is the address of the listener with the debug endpoints, it's disabled if empty.
+	AdminAddress string
+	// ServiceToken has to be sent by service-1 with every call, the API is open without it.
+	ServiceToken string
+}
+
+// LoadConfig reads the configuration from the environment.
+func LoadConfig() (Config, error) {
+	var cfg Config
+	var err error
+
+	cfg.Store.MaxKeys, err = strconv.Atoi(lib.GetEnv("STORE_MAX_KEYS", "0"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid STORE_MAX_KEYS: %w", err)
+	}
+
+	cfg.Store.TTL, err = time.ParseDuration(lib.GetEnv("STORE_TTL", "0s"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid STORE_TTL: %w", err)
+	}
+
+	cfg.Store.Quota.MaxKeys, err = strconv.Atoi(lib.GetEnv("TENANT_MAX_KEYS", "0"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TENANT_MAX_KEYS: %w", err)
+	}
+
+	cfg.Store.Quota.MaxBytes, err = strconv.Atoi(lib.GetEnv("TENANT_MAX_BYTES", "0"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TENANT_MAX_BYTES: %w", err)
+	}
+
+	cfg.Store.TenantQuotas, err = parseTenantQuotas(lib.GetEnv("TENANT_QUOTAS", ""))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TENANT_QUOTAS: %w", err)
+	}
+
+	cfg.Tenants = lib.SplitList(lib.GetEnv("TENANTS", ""))
+	cfg.Store.MetricTenants = cfg.Tenants
+	// The namespaces of unlisted tenants are summed up as OtherBaggageValue, a listed tenant of that name would collide.
+	if slices.Contains(cfg.Tenants, lib.OtherBaggageValue) {
+		return Config{}, fmt.Errorf("invalid TENANTS: %q is reserved", lib.OtherBaggageValue)
+	}
+	if _, ok := cfg.Store.TenantQuotas[lib.OtherBaggageValue]; ok {
+		return Config{}, fmt.Errorf("invalid TENANT_QUOTAS: %q is reserved", lib.OtherBaggageValue)
+	}
+
+	cfg.AdminToken = lib.GetEnv("ADMIN_TOKEN", "")
+	cfg.AdminAddress = lib.GetEnv("ADMIN_ADDRESS", "")
+	cfg.ServiceToken = lib.GetEnv("SERVICE_TOKEN", "")
+
+	cfg.FaultInjection, err = strconv.ParseBool(lib.GetEnv("FAULT_INJECTION", "false"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid FAULT_INJECTION: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// parseTenantQuotas parses a comma separated list of tenant=max_keys:max_bytes, e.g. acme=1000:1048576.
+func parseTenantQuotas(s string) (map[string]Quota, error) {
+	quotas := make(map[string]Quota)
+	for _, item := range lib.SplitList(s) {
+		tenant, limits, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not tenant=max_keys:max_bytes", item)
+		}
+		keys, bytes, ok := strings.Cut(limits, ":")
+		if !ok {
+			return nil, fmt.Errorf("%q is not tenant=max_keys:max_bytes", item)
+		}
+
+		var quota Quota
+		var err error
+		if quota.MaxKeys, err = strconv.Atoi(keys); err != nil {
+			return nil, fmt.Errorf("invalid max keys of %s: %w", tenant, err)
+		}
+		if quota.MaxBytes, err = strconv.Atoi(bytes); err != nil {
+			return nil, fmt.Errorf("invalid max bytes of %s: %w", tenant, err)
+		}
+		quotas[tenant] = quota
+	}
+
+	return quotas, nil
+}
@@ -0,0 +1,20 @@
+package service2
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigReservesOther(t *testing.T) {
+	for env, value := range map[string]string{
+		"TENANTS":       "acme,other",
+		"TENANT_QUOTAS": "other=10:1024",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "reserved") {
+				t.Fatalf("expected %s=%s to be rejected, got %v", env, value, err)
+			}
+		})
+	}
+}
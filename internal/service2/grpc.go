@@ -0,0 +1,350 @@
+package service2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"observability-demo/api/kv"
+	"observability-demo/lib"
+	"observability-demo/lib/auth"
+	"observability-demo/lib/health"
+	"observability-demo/lib/profiling"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCController serves the KV gRPC API on top of the same Store as the HTTP Controller.
+// It creates the same spans as the HTTP Controller so traces look alike for both transports.
+type GRPCController struct {
+	kv.UnimplementedKVServer
+
+	tracer  trace.Tracer
+	logger  *zap.SugaredLogger
+	store   Store
+	tenants []string
+}
+
+// NewGRPCController serves the store to the tenants, an empty list allows every tenant.
+func NewGRPCController(tracer trace.Tracer, store Store, tenants []string, logger *zap.SugaredLogger) *GRPCController {
+	return &GRPCController{
+		tracer:  tracer,
+		store:   store,
+		tenants: tenants,
+		logger:  logger,
+	}
+}
+
+func NewGRPCServer(controller *GRPCController, tp trace.TracerProvider, checks *health.Health, baggage *lib.Baggage, serviceAuth *auth.ServiceAuth) *grpc.Server {
+	// Add gRPC instrumentation for the whole server.
+	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithTracerProvider(tp))),
+		grpc.ChainUnaryInterceptor(
+			baggage.UnaryServerInterceptor(),
+			lib.RequestIDUnaryInterceptor(controller.logger),
+			serviceAuth.UnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			lib.RequestIDStreamInterceptor(controller.logger),
+			serviceAuth.StreamServerInterceptor(),
+		),
+	)
+	kv.RegisterKVServer(srv, controller)
+	healthpb.RegisterHealthServer(srv, &grpcHealth{checks: checks})
+
+	return srv
+}
+
+// grpcHealth answers gRPC health checks with the readiness of the service.
+type grpcHealth struct {
+	healthpb.UnimplementedHealthServer
+
+	checks *health.Health
+}
+
+func (h *grpcHealth) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	for _, result := range h.checks.Run(ctx) {
+		if !result.Healthy && result.Critical {
+			return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+		}
+	}
+
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (c *GRPCController) Get(ctx context.Context, req *kv.GetRequest) (*kv.GetResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, kv.KV_Get_FullMethodName)
+	defer unlabel()
+
+	ctx, err := c.withTenant(ctx, span)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span = c.tracer.Start(ctx, "in-handle-get")
+	defer span.End()
+
+	if req.GetKey() == "" {
+		return nil, invalidArgument(span, "missing key in request")
+	}
+
+	span.SetAttributes(lib.KeyKey.String(req.GetKey()))
+
+	value, err := c.store.Get(ctx, req.GetKey())
+	if err != nil {
+		return nil, failed(span, err)
+	}
+	span.SetAttributes(lib.KeyFoundKey.Bool(true))
+
+	return &kv.GetResponse{Key: req.GetKey(), Value: value}, nil
+}
+
+func (c *GRPCController) Set(ctx context.Context, req *kv.SetRequest) (*kv.SetResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, kv.KV_Set_FullMethodName)
+	defer unlabel()
+
+	ctx, err := c.withTenant(ctx, span)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span = c.tracer.Start(ctx, "in-handle-post")
+	defer span.End()
+
+	if req.GetKey() == "" {
+		return nil, invalidArgument(span, "missing key")
+	}
+	if req.GetValue() == "" {
+		return nil, invalidArgument(span, "missing value")
+	}
+
+	span.SetAttributes(lib.KeyKey.String(req.GetKey()))
+
+	if err := c.store.Set(ctx, req.GetKey(), req.GetValue()); err != nil {
+		return nil, failed(span, err)
+	}
+
+	return &kv.SetResponse{}, nil
+}
+
+func (c *GRPCController) Delete(ctx context.Context, req *kv.DeleteRequest) (*kv.DeleteResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, kv.KV_Delete_FullMethodName)
+	defer unlabel()
+
+	ctx, err := c.withTenant(ctx, span)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span = c.tracer.Start(ctx, "in-handle-delete")
+	defer span.End()
+
+	if req.GetKey() == "" {
+		return nil, invalidArgument(span, "missing key")
+	}
+
+	span.SetAttributes(lib.KeyKey.String(req.GetKey()))
+
+	if err := c.store.Delete(ctx, req.GetKey()); err != nil {
+		return nil, failed(span, err)
+	}
+
+	return &kv.DeleteResponse{}, nil
+}
+
+func (c *GRPCController) List(ctx context.Context, req *kv.ListRequest) (*kv.ListResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, kv.KV_List_FullMethodName)
+	defer unlabel()
+
+	ctx, err := c.withTenant(ctx, span)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span = c.tracer.Start(ctx, "in-handle-list")
+	defer span.End()
+
+	results, err := c.store.List(ctx, req.GetPrefix())
+	if err != nil {
+		return nil, failed(span, err)
+	}
+
+	items := make([]*kv.GetResponse, 0, len(results))
+	for _, result := range results {
+		items = append(items, &kv.GetResponse{Key: result.Key, Value: result.Value})
+	}
+
+	return &kv.ListResponse{Items: items}, nil
+}
+
+func (c *GRPCController) Watch(req *kv.WatchRequest, stream grpc.ServerStreamingServer[kv.WatchEvent]) error {
+	ctx, span := c.tracer.Start(stream.Context(), "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, kv.KV_Watch_FullMethodName)
+	defer unlabel()
+
+	ctx, err := c.withTenant(ctx, span)
+	if err != nil {
+		return err
+	}
+
+	ctx, span = c.tracer.Start(ctx, "in-handle-watch")
+	defer span.End()
+
+	events, err := c.store.Watch(ctx, req.GetPrefix())
+	if err != nil {
+		return failed(span, err)
+	}
+
+	for event := range events {
+		eventType := kv.WatchEvent_TYPE_SET
+		if event.Type == EventDelete {
+			eventType = kv.WatchEvent_TYPE_DELETE
+		}
+
+		err := stream.Send(&kv.WatchEvent{
+			Type:  eventType,
+			Key:   event.Key,
+			Value: event.Value,
+		})
+		if err != nil {
+			lib.ContextLogger(ctx, c.logger).Errorw("failed to send watch event", "error", err)
+			lib.RecordError(span, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *GRPCController) ListNamespaces(ctx context.Context, _ *kv.ListNamespacesRequest) (*kv.ListNamespacesResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, kv.KV_ListNamespaces_FullMethodName)
+	defer unlabel()
+
+	ctx, err := c.withTenant(ctx, span)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span = c.tracer.Start(ctx, "in-handle-list-namespaces")
+	defer span.End()
+
+	namespaces, err := c.store.Namespaces(ctx)
+	if err != nil {
+		return nil, failed(span, err)
+	}
+
+	resp := &kv.ListNamespacesResponse{Namespaces: make([]*kv.Namespace, 0, len(namespaces))}
+	for _, ns := range namespaces {
+		resp.Namespaces = append(resp.Namespaces, &kv.Namespace{
+			Name:     ns.Name,
+			Keys:     int64(ns.Keys),
+			Bytes:    int64(ns.Bytes),
+			MaxKeys:  int64(ns.MaxKeys),
+			MaxBytes: int64(ns.MaxBytes),
+		})
+	}
+
+	return resp, nil
+}
+
+func (c *GRPCController) DeleteNamespace(ctx context.Context, req *kv.DeleteNamespaceRequest) (*kv.DeleteNamespaceResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, kv.KV_DeleteNamespace_FullMethodName)
+	defer unlabel()
+
+	ctx, err := c.withTenant(ctx, span)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span = c.tracer.Start(ctx, "in-handle-delete-namespace")
+	defer span.End()
+
+	if req.GetNamespace() != lib.Tenant(ctx) {
+		msg := fmt.Sprintf("tenant %s can't delete namespace %s", lib.Tenant(ctx), req.GetNamespace())
+		span.SetAttributes(semconv.ErrorTypeKey.String(codes.PermissionDenied.String()))
+		return nil, status.Error(codes.PermissionDenied, msg)
+	}
+
+	deleted, err := c.store.DeleteNamespace(ctx, req.GetNamespace())
+	if err != nil {
+		return nil, failed(span, err)
+	}
+
+	return &kv.DeleteNamespaceResponse{DeletedKeys: int64(deleted)}, nil
+}
+
+// withTenant resolves the tenant from the metadata or baggage of the call and stores it in ctx.
+func (c *GRPCController) withTenant(ctx context.Context, span trace.Span) (context.Context, error) {
+	tenant, err := lib.ResolveTenantGRPC(ctx, c.tenants)
+	if err != nil {
+		return ctx, failed(span, err)
+	}
+	span.SetAttributes(lib.TenantKey.String(tenant))
+
+	return lib.WithTenant(ctx, tenant), nil
+}
+
+// invalidArgument records the mistake of the caller on the span, it's no failure of the server span.
+func invalidArgument(span trace.Span, msg string) error {
+	span.SetAttributes(semconv.ErrorTypeKey.String(codes.InvalidArgument.String()))
+
+	return status.Error(codes.InvalidArgument, msg)
+}
+
+// failed records err on the span and converts it to a gRPC status. Like the semantic conventions of server spans,
+// only internal errors fail the span, the errors of the caller only set error.type and a missing key kv.key.found.
+func failed(span trace.Span, err error) error {
+	st := toStatus(err)
+	code := status.Code(st)
+	if code == codes.NotFound {
+		lib.RecordError(span, err)
+		return st
+	}
+	if code == codes.Internal {
+		lib.RecordError(span, err)
+	}
+	span.SetAttributes(semconv.ErrorTypeKey.String(code.String()))
+
+	return st
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, lib.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, lib.ErrInvalidTenant):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, lib.ErrUnknownTenant):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, lib.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,229 @@
+package service2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"observability-demo/lib"
+	"observability-demo/lib/profiling"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type Store interface {
+	Get(context.Context, string) (string, error)
+	Set(context.Context, string, string) error
+	Delete(context.Context, string) error
+	List(context.Context, string) ([]lib.Result, error)
+	Watch(context.Context, string) (<-chan Event, error)
+	Namespaces(context.Context) ([]lib.Namespace, error)
+	DeleteNamespace(context.Context, string) (int, error)
+}
+
+type Controller struct {
+	tracer  trace.Tracer
+	logger  *zap.SugaredLogger
+	store   Store
+	tenants []string
+}
+
+// NewController serves the store to the tenants, an empty list allows every tenant.
+func NewController(tracer trace.Tracer, store Store, tenants []string, logger *zap.SugaredLogger) *Controller {
+	return &Controller{
+		tracer:  tracer,
+		store:   store,
+		tenants: tenants,
+		logger:  logger,
+	}
+}
+
+func (c *Controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(r.Context(), "in-controller-entry", trace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLPath(r.URL.Path),
+	))
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, r.Pattern)
+	defer unlabel()
+
+	ctx, ok := c.withTenant(ctx, w, r, span)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		c.handleGet(ctx, w, r)
+	case "POST":
+		c.handlePost(ctx, w, r)
+	default:
+		lib.HTTPError(w, span, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (c *Controller) handleGet(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(ctx, "in-handle-get")
+	defer span.End()
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		lib.HTTPError(w, span, http.StatusBadRequest, errors.New("missing key in request"))
+		return
+	}
+	span.SetAttributes(lib.KeyKey.String(key))
+
+	value, err := c.store.Get(ctx, key)
+	if errors.Is(err, lib.ErrNotFound) {
+		lib.HTTPError(w, span, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		lib.ContextLogger(ctx, c.logger).Errorw("failed to get value", "key", key, "error", err)
+		lib.HTTPError(w, span, http.StatusInternalServerError, err)
+		return
+	}
+	span.SetAttributes(lib.KeyFoundKey.Bool(true))
+
+	result := lib.Result{
+		Key:   key,
+		Value: value,
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		lib.ContextLogger(ctx, c.logger).Errorw("failed to marshal result", "error", err)
+		lib.HTTPError(w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	// write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(body)
+	if err != nil {
+		lib.ContextLogger(ctx, c.logger).Errorw("failed to write response", "error", err)
+		lib.RecordError(span, err)
+		return
+	}
+
+}
+
+func (c *Controller) handlePost(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(ctx, "in-handle-post")
+	defer span.End()
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		lib.HTTPError(w, span, http.StatusBadRequest, errors.New("missing key"))
+		return
+	}
+	span.SetAttributes(lib.KeyKey.String(key))
+
+	value := r.URL.Query().Get("value")
+	if value == "" {
+		lib.HTTPError(w, span, http.StatusBadRequest, errors.New("missing value"))
+		return
+	}
+
+	err := c.store.Set(ctx, key, value)
+	if errors.Is(err, lib.ErrQuotaExceeded) {
+		lib.HTTPError(w, span, http.StatusTooManyRequests, err)
+		return
+	}
+	if err != nil {
+		lib.ContextLogger(ctx, c.logger).Errorw("failed to set value", "key", key, "error", err)
+		lib.HTTPError(w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListNamespaces serves the usage and quota of the namespace of the tenant.
+func (c *Controller) ListNamespaces(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(r.Context(), "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, r.Pattern)
+	defer unlabel()
+
+	ctx, ok := c.withTenant(ctx, w, r, span)
+	if !ok {
+		return
+	}
+
+	ctx, span = c.tracer.Start(ctx, "in-handle-list-namespaces")
+	defer span.End()
+
+	namespaces, err := c.store.Namespaces(ctx)
+	if err != nil {
+		lib.HTTPError(w, span, lib.HTTPStatus(err), err)
+		return
+	}
+
+	c.writeJSON(ctx, w, span, namespaces)
+}
+
+// DeleteNamespace deletes all keys of a namespace, tenants can only delete their own namespace.
+func (c *Controller) DeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.tracer.Start(r.Context(), "in-controller-entry")
+	defer span.End()
+
+	ctx, unlabel := profiling.Label(ctx, r.Pattern)
+	defer unlabel()
+
+	ctx, ok := c.withTenant(ctx, w, r, span)
+	if !ok {
+		return
+	}
+
+	ctx, span = c.tracer.Start(ctx, "in-handle-delete-namespace")
+	defer span.End()
+
+	name := r.PathValue("namespace")
+	if name != lib.Tenant(ctx) {
+		lib.HTTPError(w, span, http.StatusForbidden, fmt.Errorf("tenant %s can't delete namespace %s", lib.Tenant(ctx), name))
+		return
+	}
+
+	deleted, err := c.store.DeleteNamespace(ctx, name)
+	if err != nil {
+		lib.HTTPError(w, span, lib.HTTPStatus(err), err)
+		return
+	}
+
+	c.writeJSON(ctx, w, span, lib.DeletedNamespace{Name: name, DeletedKeys: deleted})
+}
+
+// withTenant resolves the tenant of the request and stores it in ctx.
+// It writes the error response and returns false if the tenant is not valid or not allowed.
+func (c *Controller) withTenant(ctx context.Context, w http.ResponseWriter, r *http.Request, span trace.Span) (context.Context, bool) {
+	tenant, err := lib.ResolveTenant(r, c.tenants)
+	if err != nil {
+		lib.HTTPError(w, span, lib.HTTPStatus(err), err)
+		return ctx, false
+	}
+	span.SetAttributes(lib.TenantKey.String(tenant))
+
+	return lib.WithTenant(ctx, tenant), true
+}
+
+func (c *Controller) writeJSON(ctx context.Context, w http.ResponseWriter, span trace.Span, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		lib.ContextLogger(ctx, c.logger).Errorw("failed to marshal result", "error", err)
+		lib.HTTPError(w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		lib.ContextLogger(ctx, c.logger).Errorw("failed to write response", "error", err)
+		lib.RecordError(span, err)
+	}
+}
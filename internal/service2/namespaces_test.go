@@ -0,0 +1,60 @@
+package service2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"observability-demo/api/kv"
+	"observability-demo/lib"
+	"observability-demo/lib/telemetrytest"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestNamespacesAreScopedToTheTenant(t *testing.T) {
+	store, _ := newTestStore(t, StoreConfig{TenantQuotas: map[string]Quota{"globex": {MaxKeys: 10}}})
+	for _, tenant := range []string{"acme", "initech"} {
+		if err := store.Set(lib.WithTenant(context.Background(), tenant), "foo", "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tp, _ := telemetrytest.NewTracerProvider(t)
+	tracer := tp.Tracer("controller")
+
+	t.Run("http", func(t *testing.T) {
+		controller := NewController(tracer, store, nil, zap.NewNop().Sugar())
+		req := httptest.NewRequest(http.MethodGet, "/namespaces", nil)
+		req.Header.Set(lib.TenantHeader, "acme")
+		rec := httptest.NewRecorder()
+		controller.ListNamespaces(rec, req)
+
+		var namespaces []lib.Namespace
+		if err := json.Unmarshal(rec.Body.Bytes(), &namespaces); err != nil {
+			t.Fatalf("failed to decode %q: %s", rec.Body.String(), err)
+		}
+		if len(namespaces) != 1 || namespaces[0].Name != "acme" || namespaces[0].Keys != 1 {
+			t.Fatalf("expected only the namespace of acme, got %+v", namespaces)
+		}
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		controller := NewGRPCController(tracer, store, nil, zap.NewNop().Sugar())
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "initech"))
+		resp, err := controller.ListNamespaces(ctx, &kv.ListNamespacesRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.GetNamespaces()) != 1 || resp.GetNamespaces()[0].GetName() != "initech" {
+			t.Fatalf("expected only the namespace of initech, got %v", resp.GetNamespaces())
+		}
+	})
+
+	// A tenant without keys sees nothing, not even the explicit quotas of others.
+	namespaces, err := store.Namespaces(lib.WithTenant(context.Background(), "hooli"))
+	if err != nil || len(namespaces) != 0 {
+		t.Fatalf("expected no namespaces, got %+v, %v", namespaces, err)
+	}
+}
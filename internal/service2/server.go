@@ -0,0 +1,115 @@
+package service2
+
+import (
+	"net/http"
+	"observability-demo/lib"
+	"observability-demo/lib/admin"
+	"observability-demo/lib/auth"
+	"observability-demo/lib/fault"
+	"observability-demo/lib/health"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// NewServer serves the API, metrics and health checks.
+// If faults is not nil, its rules apply to the API. admin serves the endpoints below /admin/.
+// Every API request is written to the access log on log. baggage may be nil.
+// If serviceAuth is not nil, the API rejects calls without the service token.
+// It returns the handler and the patterns it serves.
+func NewServer(controller *Controller, tp trace.TracerProvider, reg *prometheus.Registry, checks *health.Health, faults *fault.Injector, admin http.Handler, log *zap.SugaredLogger, baggage *lib.Baggage, serviceAuth *auth.ServiceAuth) (http.Handler, []string) {
+	mux := http.NewServeMux()
+	var routes []string
+
+	// handleFunc is a replacement for mux.HandleFunc
+	// which enriches the handler's HTTP instrumentation with the pattern as the http.route.
+	handleFunc := func(pattern string, handlerFunc func(http.ResponseWriter, *http.Request)) {
+		var handler http.Handler = http.HandlerFunc(handlerFunc)
+		if faults != nil {
+			handler = faults.Middleware(handler)
+		}
+		handler = serviceAuth.Middleware(handler)
+		handler = lib.RequestLogging(log, handler)
+		// Configure the "http.route" for the HTTP instrumentation.
+		handler = otelhttp.WithRouteTag(pattern, handler)
+		mux.Handle(pattern, handler)
+		routes = append(routes, pattern)
+	}
+
+	handleFunc("/", controller.ServeHTTP)
+	handleFunc("GET /namespaces", controller.ListNamespaces)
+	handleFunc("DELETE /namespaces/{namespace}", controller.DeleteNamespace)
+	mux.Handle(lib.MetricsPath, lib.MetricsHandler(reg))
+	checks.Handle(mux)
+	mux.Handle(lib.AdminPrefix, admin)
+	routes = append(routes, lib.MetricsPath, health.LivezPath, health.ReadyzPath, health.HealthzPath, lib.AdminPrefix)
+
+	metrics := lib.NewHTTPMetrics(reg, baggage)
+
+	// Add HTTP instrumentation for the whole server.
+	handler := otelhttp.NewHandler(
+		baggage.Middleware(metrics.Middleware(lib.WithTraceResponse(mux))),
+		"/",
+		otelhttp.WithTracerProvider(tp),
+		otelhttp.WithFilter(lib.TracedRequest),
+	)
+	return handler, routes
+}
+
+// Service is service-2 wired up, ready to be served.
+type Service struct {
+	Handler    http.Handler
+	GRPCServer *grpc.Server
+	// Routes are the patterns served by Handler and the methods served by GRPCServer.
+	Routes []string
+	Store  *MemoryStore
+	Health *health.Health
+	// Faults is nil unless fault injection is enabled.
+	Faults *fault.Injector
+}
+
+func New(cfg Config, tel lib.Telemetry) *Service {
+	httpSrvLogger := lib.CreateChildLogger(tel.Logger, "http-server")
+	grpcLogger := lib.CreateChildLogger(tel.Logger, "grpc-server")
+	storeLogger := lib.CreateChildLogger(tel.Logger, "store")
+
+	store := NewMemoryStore(cfg.Store, tel.TracerProvider.Tracer("store"), storeLogger)
+	controller := NewController(tel.TracerProvider.Tracer("controller"), store, cfg.Tenants, httpSrvLogger)
+
+	checks := health.New()
+	checks.Register("store", store.CheckWritable, health.Liveness())
+	checks.Register("trace-exporter", lib.CheckTraceExporter, health.Informational())
+	tel.Registry.MustRegister(NewStoreCollector(store), checks)
+
+	grpcController := NewGRPCController(tel.TracerProvider.Tracer("controller"), store, cfg.Tenants, grpcLogger)
+
+	serviceAuth := auth.NewServiceAuth(cfg.ServiceToken, tel.Registry, lib.CreateChildLogger(tel.Logger, "audit"))
+	if serviceAuth == nil {
+		tel.Logger.Sugar().Warn("no service token is configured, the API is open to everyone")
+	}
+
+	adminMux := http.NewServeMux()
+	if levels := lib.LogLevelsOf(tel.Logger); levels != nil {
+		levels.Handle(adminMux, cfg.AdminToken)
+	}
+	var faults *fault.Injector
+	if cfg.FaultInjection {
+		faults = fault.NewInjector(tel.Registry, lib.CreateChildLogger(tel.Logger, "fault-injection"))
+		faults.Handle(adminMux, cfg.AdminToken)
+	}
+
+	handler, routes := NewServer(controller, tel.TracerProvider, tel.Registry, checks, faults, adminMux, httpSrvLogger, tel.Baggage, serviceAuth)
+	grpcServer := NewGRPCServer(grpcController, tel.TracerProvider, checks, tel.Baggage, serviceAuth)
+
+	return &Service{
+		Handler:    handler,
+		GRPCServer: grpcServer,
+		Routes:     append(routes, admin.GRPCRoutes(grpcServer)...),
+		Store:      store,
+		Health:     checks,
+		Faults:     faults,
+	}
+}
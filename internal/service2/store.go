@@ -0,0 +1,523 @@
+package service2
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"observability-demo/lib"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type EventType int
+
+const (
+	EventSet EventType = iota
+	EventDelete
+)
+
+// Event describes a single change to the store.
+type Event struct {
+	Type  EventType
+	Key   string
+	Value string
+}
+
+type watcher struct {
+	namespace string
+	prefix    string
+	events    chan Event
+}
+
+// Quota limits the keys of a namespace, zero means unlimited.
+type Quota struct {
+	MaxKeys  int
+	MaxBytes int
+}
+
+type StoreConfig struct {
+	// MaxKeys limits the number of keys of all namespaces, the least recently written key is evicted first.
+	// Zero means unlimited.
+	MaxKeys int
+	// TTL expires keys after they were last written. Zero means keys never expire.
+	TTL time.Duration
+	// Quota applies to every namespace which has no entry in TenantQuotas.
+	// Writes exceeding the quota are rejected instead of evicting other keys.
+	Quota        Quota
+	TenantQuotas map[string]Quota
+	// MetricTenants are exported with their name in the store_tenant_* metrics, like the tenants in TenantQuotas.
+	// All other namespaces are summed up as OtherBaggageValue, their names are chosen by the clients.
+	MetricTenants []string
+}
+
+func (c StoreConfig) quota(tenant string) Quota {
+	if quota, ok := c.TenantQuotas[tenant]; ok {
+		return quota
+	}
+
+	return c.Quota
+}
+
+// tenantLabel bounds the cardinality of the tenant label of the metrics.
+func (c StoreConfig) tenantLabel(tenant string) string {
+	if _, ok := c.TenantQuotas[tenant]; ok || slices.Contains(c.MetricTenants, tenant) {
+		return tenant
+	}
+
+	return lib.OtherBaggageValue
+}
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+	// element points into MemoryStore.order.
+	element *list.Element
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// nsKey identifies a key across all namespaces.
+type nsKey struct {
+	namespace string
+	key       string
+}
+
+// namespace is the isolated key space of a tenant, it exists as long as it has keys.
+type namespace struct {
+	entries map[string]*entry
+	bytes   int
+}
+
+// MemoryStore keeps a namespace per tenant, the tenant is taken from the context, see lib.Tenant.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	namespaces map[string]*namespace
+	// order holds the nsKeys from least to most recently written.
+	order    *list.List
+	bytes    int
+	watchers map[*watcher]struct{}
+
+	cfg   StoreConfig
+	stats *storeStats
+
+	log    *zap.SugaredLogger
+	tracer trace.Tracer
+}
+
+func NewMemoryStore(cfg StoreConfig, tracer trace.Tracer, logger *zap.SugaredLogger) *MemoryStore {
+	return &MemoryStore{
+		namespaces: make(map[string]*namespace),
+		order:      list.New(),
+		watchers:   make(map[*watcher]struct{}),
+		cfg:        cfg,
+		stats:      newStoreStats(),
+		tracer:     tracer,
+		log:        logger,
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	ctx, span := s.startSpan(ctx, opGet, lib.KeyKey.String(key))
+	defer span.End()
+	defer s.stats.observe(ctx, opGet, time.Now())
+
+	k := nsKey{namespace: lib.Tenant(ctx), key: key}
+
+	s.mu.RLock()
+	e, ok := s.lookup(k)
+	expired := ok && e.expired(time.Now())
+	s.mu.RUnlock()
+
+	if expired {
+		s.mu.Lock()
+		s.expire(time.Now())
+		e, ok = s.lookup(k)
+		s.mu.Unlock()
+	}
+
+	span.SetAttributes(lib.KeyFoundKey.Bool(ok))
+	if ok {
+		s.stats.hits.Add(1)
+		lib.ContextLogger(ctx, s.log).Debugw("found key", "key", key, "value", e.value)
+
+		return e.value, nil
+	}
+
+	s.stats.misses.Add(1)
+
+	return "", fmt.Errorf("key %s not found in store: %w", key, lib.ErrNotFound)
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key, value string) error {
+	ctx, span := s.startSpan(ctx, opSet, lib.KeyKey.String(key))
+	defer span.End()
+	defer s.stats.observe(ctx, opSet, time.Now())
+
+	k := nsKey{namespace: lib.Tenant(ctx), key: key}
+
+	s.mu.Lock()
+	s.expire(time.Now())
+	if err := s.checkQuota(ctx, k, value); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	if e, ok := s.lookup(k); ok {
+		s.remove(k, e)
+	}
+
+	e := &entry{value: value}
+	if s.cfg.TTL > 0 {
+		e.expiresAt = time.Now().Add(s.cfg.TTL)
+	}
+	e.element = s.order.PushBack(k)
+	s.add(k, e)
+	s.notify(k.namespace, Event{Type: EventSet, Key: key, Value: value})
+
+	for s.cfg.MaxKeys > 0 && s.order.Len() > s.cfg.MaxKeys {
+		oldest := s.order.Front().Value.(nsKey)
+		oldestEntry, _ := s.lookup(oldest)
+		s.remove(oldest, oldestEntry)
+		s.notify(oldest.namespace, Event{Type: EventDelete, Key: oldest.key})
+		s.stats.evictions.Add(1)
+		s.log.Infow("evicted key", "key", oldest.key, "namespace", oldest.namespace)
+	}
+	s.mu.Unlock()
+
+	log := lib.ContextLogger(ctx, s.log)
+	log.Infow("set key", "key", key)
+	log.Debugw("set value", "key", key, "value", value)
+
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	ctx, span := s.startSpan(ctx, opDelete, lib.KeyKey.String(key))
+	defer span.End()
+	defer s.stats.observe(ctx, opDelete, time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+
+	k := nsKey{namespace: lib.Tenant(ctx), key: key}
+	e, ok := s.lookup(k)
+	span.SetAttributes(lib.KeyFoundKey.Bool(ok))
+	if !ok {
+		return fmt.Errorf("key %s not found in store: %w", key, lib.ErrNotFound)
+	}
+	s.remove(k, e)
+	s.notify(k.namespace, Event{Type: EventDelete, Key: key})
+
+	lib.ContextLogger(ctx, s.log).Infof("deleted key %s", key)
+
+	return nil
+}
+
+// List returns all key-value pairs whose key starts with prefix, sorted by key.
+func (s *MemoryStore) List(ctx context.Context, prefix string) ([]lib.Result, error) {
+	ctx, span := s.startSpan(ctx, opList, attribute.String("kv.prefix", prefix))
+	defer span.End()
+	defer s.stats.observe(ctx, opList, time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+
+	results := make([]lib.Result, 0)
+	if n, ok := s.namespaces[lib.Tenant(ctx)]; ok {
+		for k, e := range n.entries {
+			if strings.HasPrefix(k, prefix) {
+				results = append(results, lib.Result{Key: k, Value: e.value})
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Key < results[j].Key
+	})
+	span.SetAttributes(semconv.DBResponseReturnedRows(len(results)))
+
+	return results, nil
+}
+
+// Watch returns a channel that receives every change to keys of the namespace starting with prefix
+// until ctx is cancelled.
+func (s *MemoryStore) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	_, span := s.startSpan(ctx, "watch", attribute.String("kv.prefix", prefix))
+	defer span.End()
+
+	w := &watcher{
+		namespace: lib.Tenant(ctx),
+		prefix:    prefix,
+		events:    make(chan Event, 64),
+	}
+
+	s.mu.Lock()
+	s.watchers[w] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		delete(s.watchers, w)
+		close(w.events)
+		s.mu.Unlock()
+	}()
+
+	return w.events, nil
+}
+
+// Namespaces returns the usage and quota of the namespace of the tenant, if it has keys or an explicit quota.
+// Tenants can't see the namespaces of each other, like they can only delete their own.
+func (s *MemoryStore) Namespaces(ctx context.Context) ([]lib.Namespace, error) {
+	ctx, span := s.startSpan(ctx, opListNamespaces)
+	defer span.End()
+	defer s.stats.observe(ctx, opListNamespaces, time.Now())
+
+	s.mu.Lock()
+	s.expire(time.Now())
+	s.mu.Unlock()
+
+	tenant := lib.Tenant(ctx)
+	namespaces := slices.DeleteFunc(s.namespaceUsage(), func(ns lib.Namespace) bool {
+		return ns.Name != tenant
+	})
+	span.SetAttributes(semconv.DBResponseReturnedRows(len(namespaces)))
+
+	return namespaces, nil
+}
+
+// DeleteNamespace deletes all keys of a namespace and returns how many were deleted.
+func (s *MemoryStore) DeleteNamespace(ctx context.Context, name string) (int, error) {
+	ctx, span := s.startSpan(ctx, opDeleteNamespace, semconv.DBNamespace(name))
+	defer span.End()
+	defer s.stats.observe(ctx, opDeleteNamespace, time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.namespaces[name]
+	if !ok {
+		span.SetAttributes(semconv.DBResponseReturnedRows(0))
+		return 0, nil
+	}
+
+	deleted := len(n.entries)
+	for key, e := range n.entries {
+		s.remove(nsKey{namespace: name, key: key}, e)
+		s.notify(name, Event{Type: EventDelete, Key: key})
+	}
+	span.SetAttributes(semconv.DBResponseReturnedRows(deleted))
+
+	lib.ContextLogger(ctx, s.log).Infow("deleted namespace", "namespace", name, "keys", deleted)
+
+	return deleted, nil
+}
+
+// namespaceUsage returns the usage of the namespaces sorted by name, it doesn't expire keys.
+func (s *MemoryStore) namespaceUsage() []lib.Namespace {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	namespaces := make([]lib.Namespace, 0, len(s.namespaces)+len(s.cfg.TenantQuotas))
+	usage := func(name string, keys, bytes int) {
+		quota := s.cfg.quota(name)
+		namespaces = append(namespaces, lib.Namespace{
+			Name:     name,
+			Keys:     keys,
+			Bytes:    bytes,
+			MaxKeys:  quota.MaxKeys,
+			MaxBytes: quota.MaxBytes,
+		})
+	}
+	for name, n := range s.namespaces {
+		usage(name, len(n.entries), n.bytes)
+	}
+	for name := range s.cfg.TenantQuotas {
+		if _, ok := s.namespaces[name]; !ok {
+			usage(name, 0, 0)
+		}
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+
+	return namespaces
+}
+
+// dbSystem identifies the store in the db.system.name attribute of its spans.
+const dbSystem = "memory"
+
+// startSpan starts the span of a store operation with the database client attributes,
+// the namespace is the tenant of the request unless attrs override it.
+func (s *MemoryStore) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "in-store-"+operation, trace.WithAttributes(append([]attribute.KeyValue{
+		semconv.DBSystemNameKey.String(dbSystem),
+		semconv.DBOperationName(strings.ToUpper(operation)),
+		semconv.DBNamespace(lib.Tenant(ctx)),
+	}, attrs...)...))
+}
+
+// ExpireLoop removes expired keys every interval until ctx is cancelled.
+// Expired keys are never returned, the loop only frees their memory.
+func (s *MemoryStore) ExpireLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			s.expire(now)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// CheckWritable is a health check which fails if the store can't be locked for writing in time.
+func (s *MemoryStore) CheckWritable(ctx context.Context) error {
+	for !s.mu.TryLock() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("store is not writable: %w", ctx.Err())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Len returns the number of keys of all namespaces and their approximate size in bytes.
+func (s *MemoryStore) Len() (int, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.order.Len(), s.bytes
+}
+
+// expire must be called with s.mu held.
+func (s *MemoryStore) expire(now time.Time) {
+	if s.cfg.TTL <= 0 {
+		return
+	}
+
+	// All keys share the same TTL, so they expire in the order they were written.
+	for el := s.order.Front(); el != nil; {
+		k := el.Value.(nsKey)
+		e, _ := s.lookup(k)
+		if !e.expired(now) {
+			return
+		}
+
+		el = el.Next()
+		s.remove(k, e)
+		s.notify(k.namespace, Event{Type: EventDelete, Key: k.key})
+		s.stats.expirations.Add(1)
+		s.log.Infow("expired key", "key", k.key, "namespace", k.namespace)
+	}
+}
+
+// checkQuota rejects writing value to k if the namespace would exceed its quota.
+// It must be called with s.mu held.
+func (s *MemoryStore) checkQuota(ctx context.Context, k nsKey, value string) error {
+	quota := s.cfg.quota(k.namespace)
+	if quota.MaxKeys <= 0 && quota.MaxBytes <= 0 {
+		return nil
+	}
+
+	keys, bytes := 1, entrySize(k.key, value)
+	if n, ok := s.namespaces[k.namespace]; ok {
+		keys += len(n.entries)
+		bytes += n.bytes
+		if e, ok := n.entries[k.key]; ok {
+			keys--
+			bytes -= entrySize(k.key, e.value)
+		}
+	}
+
+	var exceeded string
+	switch {
+	case quota.MaxKeys > 0 && keys > quota.MaxKeys:
+		exceeded = quotaKeys
+	case quota.MaxBytes > 0 && bytes > quota.MaxBytes:
+		exceeded = quotaBytes
+	default:
+		return nil
+	}
+	lib.IncWithExemplar(ctx, s.stats.quotaRejections.WithLabelValues(s.cfg.tenantLabel(k.namespace), exceeded))
+
+	return fmt.Errorf("setting key %s exceeds the %s quota of namespace %s: %w", k.key, exceeded, k.namespace, lib.ErrQuotaExceeded)
+}
+
+// lookup must be called with s.mu held.
+func (s *MemoryStore) lookup(k nsKey) (*entry, bool) {
+	n, ok := s.namespaces[k.namespace]
+	if !ok {
+		return nil, false
+	}
+	e, ok := n.entries[k.key]
+
+	return e, ok
+}
+
+// add must be called with s.mu held.
+func (s *MemoryStore) add(k nsKey, e *entry) {
+	n, ok := s.namespaces[k.namespace]
+	if !ok {
+		n = &namespace{entries: make(map[string]*entry)}
+		s.namespaces[k.namespace] = n
+	}
+	n.entries[k.key] = e
+	n.bytes += entrySize(k.key, e.value)
+	s.bytes += entrySize(k.key, e.value)
+}
+
+// remove must be called with s.mu held, the namespace is removed with its last key.
+func (s *MemoryStore) remove(k nsKey, e *entry) {
+	s.order.Remove(e.element)
+	n := s.namespaces[k.namespace]
+	delete(n.entries, k.key)
+	n.bytes -= entrySize(k.key, e.value)
+	s.bytes -= entrySize(k.key, e.value)
+	if len(n.entries) == 0 {
+		delete(s.namespaces, k.namespace)
+	}
+}
+
+// notify must be called with s.mu held.
+func (s *MemoryStore) notify(namespace string, event Event) {
+	for w := range s.watchers {
+		if w.namespace != namespace || !strings.HasPrefix(event.Key, w.prefix) {
+			continue
+		}
+
+		select {
+		case w.events <- event:
+		default:
+			s.log.Warnf("dropping event for key %s, watcher is too slow", event.Key)
+		}
+	}
+}
+
+// entryOverhead approximates the memory used by the map entry, the list element and the entry struct.
+const entryOverhead = 128
+
+func entrySize(key, value string) int {
+	return len(key) + len(value) + entryOverhead
+}
@@ -0,0 +1,197 @@
+package service2
+
+import (
+	"context"
+	"observability-demo/lib"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	opGet    = "get"
+	opSet    = "set"
+	opDelete = "delete"
+	opList   = "list"
+
+	opListNamespaces  = "list_namespaces"
+	opDeleteNamespace = "delete_namespace"
+
+	// quotaKeys and quotaBytes are the reasons of quota rejections.
+	quotaKeys  = "keys"
+	quotaBytes = "bytes"
+)
+
+// storeStats is updated by the MemoryStore on every operation and read by the StoreCollector.
+type storeStats struct {
+	ops         map[string]*atomic.Uint64
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+	latency     *prometheus.HistogramVec
+	// quotaRejections is labeled by tenant, only tenants with a quota can be rejected.
+	// Tenants which are not labeled by name are counted as OtherBaggageValue.
+	quotaRejections *prometheus.CounterVec
+}
+
+func newStoreStats() *storeStats {
+	return &storeStats{
+		ops: map[string]*atomic.Uint64{
+			opGet:    {},
+			opSet:    {},
+			opDelete: {},
+			opList:   {},
+
+			opListNamespaces:  {},
+			opDeleteNamespace: {},
+		},
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:                            "store_operation_duration_seconds",
+			Help:                            "Duration of store operations.",
+			Buckets:                         prometheus.ExponentialBuckets(0.000001, 4, 10),
+			NativeHistogramBucketFactor:     1.1,
+			NativeHistogramMaxBucketNumber:  100,
+			NativeHistogramMinResetDuration: time.Hour,
+		}, []string{"operation"}),
+		quotaRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "store_tenant_quota_rejections_total",
+			Help: "Total number of writes rejected because they exceeded the quota of the tenant.",
+		}, []string{"tenant", "quota"}),
+	}
+}
+
+// observe records an operation which started at start, use it with defer.
+func (s *storeStats) observe(ctx context.Context, op string, start time.Time) {
+	s.ops[op].Add(1)
+
+	lib.ObserveWithExemplar(ctx, s.latency.WithLabelValues(op), time.Since(start).Seconds())
+}
+
+// StoreCollector exposes the state of a MemoryStore as Prometheus metrics.
+type StoreCollector struct {
+	store *MemoryStore
+
+	keys        *prometheus.Desc
+	bytes       *prometheus.Desc
+	operations  *prometheus.Desc
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	hitRatio    *prometheus.Desc
+	evictions   *prometheus.Desc
+	expirations *prometheus.Desc
+
+	tenantKeys     *prometheus.Desc
+	tenantBytes    *prometheus.Desc
+	tenantMaxKeys  *prometheus.Desc
+	tenantMaxBytes *prometheus.Desc
+}
+
+func NewStoreCollector(store *MemoryStore) *StoreCollector {
+	return &StoreCollector{
+		store: store,
+		keys: prometheus.NewDesc(
+			"store_keys", "Number of keys in the store.", nil, nil,
+		),
+		bytes: prometheus.NewDesc(
+			"store_bytes", "Approximate memory used by the keys and values in the store.", nil, nil,
+		),
+		operations: prometheus.NewDesc(
+			"store_operations_total", "Total number of store operations.", []string{"operation"}, nil,
+		),
+		hits: prometheus.NewDesc(
+			"store_hits_total", "Total number of gets which found the key.", nil, nil,
+		),
+		misses: prometheus.NewDesc(
+			"store_misses_total", "Total number of gets which did not find the key.", nil, nil,
+		),
+		hitRatio: prometheus.NewDesc(
+			"store_hit_ratio", "Ratio of hits to all gets since the start.", nil, nil,
+		),
+		evictions: prometheus.NewDesc(
+			"store_evictions_total", "Total number of keys evicted because the store was full.", nil, nil,
+		),
+		expirations: prometheus.NewDesc(
+			"store_expirations_total", "Total number of keys removed because their TTL passed.", nil, nil,
+		),
+		tenantKeys: prometheus.NewDesc(
+			"store_tenant_keys", "Number of keys in the namespace of the tenant.", []string{"tenant"}, nil,
+		),
+		tenantBytes: prometheus.NewDesc(
+			"store_tenant_bytes", "Approximate memory used by the namespace of the tenant.", []string{"tenant"}, nil,
+		),
+		tenantMaxKeys: prometheus.NewDesc(
+			"store_tenant_quota_max_keys", "Key quota of the tenant, only exposed if limited.", []string{"tenant"}, nil,
+		),
+		tenantMaxBytes: prometheus.NewDesc(
+			"store_tenant_quota_max_bytes", "Byte quota of the tenant, only exposed if limited.", []string{"tenant"}, nil,
+		),
+	}
+}
+
+func (c *StoreCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.keys
+	ch <- c.bytes
+	ch <- c.operations
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.hitRatio
+	ch <- c.evictions
+	ch <- c.expirations
+	ch <- c.tenantKeys
+	ch <- c.tenantBytes
+	ch <- c.tenantMaxKeys
+	ch <- c.tenantMaxBytes
+	c.store.stats.latency.Describe(ch)
+	c.store.stats.quotaRejections.Describe(ch)
+}
+
+func (c *StoreCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.store.stats
+	keys, bytes := c.store.Len()
+
+	ch <- prometheus.MustNewConstMetric(c.keys, prometheus.GaugeValue, float64(keys))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(bytes))
+	for op, count := range stats.ops {
+		ch <- prometheus.MustNewConstMetric(c.operations, prometheus.CounterValue, float64(count.Load()), op)
+	}
+
+	hits := float64(stats.hits.Load())
+	misses := float64(stats.misses.Load())
+	ratio := 0.0
+	if hits+misses > 0 {
+		ratio = hits / (hits + misses)
+	}
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, hits)
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, misses)
+	ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, ratio)
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.evictions.Load()))
+	ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(stats.expirations.Load()))
+
+	// Namespaces of tenants which are not labeled by name are summed up, so the number of series is bounded
+	// by the configured tenants. The quotas of the sum are not exposed, they apply to each namespace.
+	var other lib.Namespace
+	for _, ns := range c.store.namespaceUsage() {
+		if c.store.cfg.tenantLabel(ns.Name) != ns.Name {
+			other.Keys += ns.Keys
+			other.Bytes += ns.Bytes
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.tenantKeys, prometheus.GaugeValue, float64(ns.Keys), ns.Name)
+		ch <- prometheus.MustNewConstMetric(c.tenantBytes, prometheus.GaugeValue, float64(ns.Bytes), ns.Name)
+		if ns.MaxKeys > 0 {
+			ch <- prometheus.MustNewConstMetric(c.tenantMaxKeys, prometheus.GaugeValue, float64(ns.MaxKeys), ns.Name)
+		}
+		if ns.MaxBytes > 0 {
+			ch <- prometheus.MustNewConstMetric(c.tenantMaxBytes, prometheus.GaugeValue, float64(ns.MaxBytes), ns.Name)
+		}
+	}
+	if other.Keys > 0 {
+		ch <- prometheus.MustNewConstMetric(c.tenantKeys, prometheus.GaugeValue, float64(other.Keys), lib.OtherBaggageValue)
+		ch <- prometheus.MustNewConstMetric(c.tenantBytes, prometheus.GaugeValue, float64(other.Bytes), lib.OtherBaggageValue)
+	}
+
+	stats.latency.Collect(ch)
+	stats.quotaRejections.Collect(ch)
+}
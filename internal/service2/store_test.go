@@ -0,0 +1,104 @@
+package service2
+
+import (
+	"context"
+	"errors"
+	"observability-demo/lib"
+	"observability-demo/lib/telemetrytest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.uber.org/zap"
+)
+
+func newTestStore(t *testing.T, cfg StoreConfig) (*MemoryStore, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	tp, spans := telemetrytest.NewTracerProvider(t)
+
+	return NewMemoryStore(cfg, tp.Tracer("store"), zap.NewNop().Sugar()), spans
+}
+
+func TestStoreSpans(t *testing.T) {
+	store, spans := newTestStore(t, StoreConfig{})
+	tp, _ := telemetrytest.NewTracerProvider(t)
+	ctx, parent := tp.Tracer("test").Start(lib.WithTenant(context.Background(), "acme"), "parent")
+
+	if err := store.Set(ctx, "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	for _, op := range []string{opSet, opGet} {
+		span := telemetrytest.AssertSpan(t, spans, "in-store-"+op,
+			semconv.DBSystemNameKey.String(dbSystem),
+			semconv.DBOperationName(strings.ToUpper(op)),
+			semconv.DBNamespace("acme"),
+			lib.KeyKey.String("foo"),
+		)
+		telemetrytest.AssertNotError(t, span)
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("span %s is not a child of the span in the context", span.Name())
+		}
+	}
+	telemetrytest.AssertSpan(t, spans, "in-store-get", lib.KeyFoundKey.Bool(true))
+}
+
+func TestStoreGetMissingKey(t *testing.T) {
+	store, spans := newTestStore(t, StoreConfig{})
+
+	_, err := store.Get(context.Background(), "missing")
+	if !errors.Is(err, lib.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// A miss is an expected outcome, so the span is not marked as failed.
+	span := telemetrytest.AssertSpan(t, spans, "in-store-get",
+		semconv.DBNamespace(lib.DefaultTenant),
+		lib.KeyKey.String("missing"),
+		lib.KeyFoundKey.Bool(false),
+	)
+	telemetrytest.AssertNotError(t, span)
+}
+
+func TestStoreDeleteSpans(t *testing.T) {
+	store, spans := newTestStore(t, StoreConfig{})
+	ctx := lib.WithTenant(context.Background(), "acme")
+
+	if err := store.Set(ctx, "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "foo"); !errors.Is(err, lib.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for the second delete, got %v", err)
+	}
+
+	telemetrytest.AssertSpan(t, spans, "in-store-delete", semconv.DBOperationName("DELETE"), lib.KeyFoundKey.Bool(true))
+	telemetrytest.AssertSpan(t, spans, "in-store-delete", semconv.DBOperationName("DELETE"), lib.KeyFoundKey.Bool(false))
+}
+
+func TestStoreNamespaceSpans(t *testing.T) {
+	store, spans := newTestStore(t, StoreConfig{})
+	if err := store.Set(lib.WithTenant(context.Background(), "acme"), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The deleted namespace overrides the tenant of the caller in db.namespace.
+	deleted, err := store.DeleteNamespace(lib.WithTenant(context.Background(), "admin"), "acme")
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected 1 deleted key, got %d, %v", deleted, err)
+	}
+
+	telemetrytest.AssertSpan(t, spans, "in-store-"+opDeleteNamespace,
+		semconv.DBSystemNameKey.String(dbSystem),
+		semconv.DBOperationName(strings.ToUpper(opDeleteNamespace)),
+		semconv.DBNamespace("acme"),
+	)
+}
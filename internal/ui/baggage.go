@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// baggageHeaderPrefix is followed by the member, e.g. X-Baggage-Tenant: acme.
+const baggageHeaderPrefix = "X-Baggage-"
+
+// withBaggage adds the members with the given keys to the baggage of the request,
+// their values are taken from the form field of the same name or from the X-Baggage- header.
+// The outgoing requests to service-1 carry the baggage, so the whole trace is attributed to the tenant, user or experiment.
+func withBaggage(keys []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bag := baggage.FromContext(r.Context())
+
+		changed := false
+		for _, key := range keys {
+			value := r.FormValue(key)
+			if value == "" {
+				value = r.Header.Get(baggageHeaderPrefix + key)
+			}
+			if value == "" {
+				continue
+			}
+
+			member, err := baggage.NewMemberRaw(key, value)
+			if err != nil {
+				http.Error(w, "invalid baggage "+key, http.StatusBadRequest)
+				return
+			}
+			if bag, err = bag.SetMember(member); err != nil {
+				http.Error(w, "invalid baggage "+key, http.StatusBadRequest)
+				return
+			}
+			changed = true
+		}
+
+		if changed {
+			r = r.WithContext(baggage.ContextWithBaggage(r.Context(), bag))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
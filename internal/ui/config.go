@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"fmt"
+	"observability-demo/lib"
+	"strconv"
+)
+
+type Config struct {
+	// ServerAddress is the base URL of service-1.
+	ServerAddress string
+	// ServerAPIKey authenticates the ui at service-1 if its API requires authentication.
+	ServerAPIKey           string
+	TraceViewerOTLPAddress string
+	TraceViewerCapacity    int
+	// AdminToken protects the admin endpoints, they are disabled without it.
+	AdminToken string
+}
+
+// LoadConfig reads the configuration from the environment.
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		ServerAddress:          lib.GetEnv("SERVER_ADDRESS", "http://localhost:4040"),
+		ServerAPIKey:           lib.GetEnv("SERVER_API_KEY", ""),
+		TraceViewerOTLPAddress: lib.GetEnv("TRACE_VIEWER_OTLP_ADDRESS", ":4318"),
+		AdminToken:             lib.GetEnv("ADMIN_TOKEN", ""),
+	}
+
+	var err error
+	cfg.TraceViewerCapacity, err = strconv.Atoi(lib.GetEnv("TRACE_VIEWER_CAPACITY", "1000"))
+	if err != nil || cfg.TraceViewerCapacity <= 0 {
+		return Config{}, fmt.Errorf("invalid TRACE_VIEWER_CAPACITY %q", lib.GetEnv("TRACE_VIEWER_CAPACITY", ""))
+	}
+
+	return cfg, nil
+}
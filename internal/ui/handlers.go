@@ -0,0 +1,211 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"observability-demo/lib"
+	"observability-demo/lib/auth"
+	"observability-demo/lib/health"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tmpl = template.Must(template.New("ui").Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Key-Value Service</title>
+</head>
+<body>
+	<h1>Key-Value Service</h1>
+	<p><a href="/traces/">Local trace viewer</a></p>
+	<h2>Set Key-Value Pair</h2>
+	<form method="POST" action="/set">
+		<label for="key">Key:</label>
+		<input type="text" id="key" name="key" required>
+		<br>
+		<label for="value">Value:</label>
+		<input type="text" id="value" name="value" required>
+		<br>
+		{{template "baggage" .Baggage}}
+		<button type="submit">Set</button>
+	</form>
+	<h2>Get Value by Key</h2>
+	<form method="GET" action="/get">
+		<label for="key">Key:</label>
+		<input type="text" id="key" name="key" required>
+		<br>
+		{{template "baggage" .Baggage}}
+		<button type="submit">Get</button>
+	</form>
+	{{if .Response}}
+		<h3>Response:</h3>
+		<p>{{.Response}}</p>
+	{{end}}
+	{{if .TraceID}}
+		<p>Trace ID: <a href="{{.TraceURL}}" target="_blank">{{.TraceID}}</a></p>
+	{{end}}
+</body>
+</html>
+{{define "baggage"}}
+	{{range .}}
+		<label for="{{.Key}}">Baggage {{.Key}}:</label>
+		<input type="text" id="{{.Key}}" name="{{.Key}}" value="{{.Value}}">
+		<br>
+	{{end}}
+{{end}}
+`))
+
+// handlers serve the forms and forward the requests to service-1.
+type handlers struct {
+	serverAddress string
+	apiKey        string
+	client        *http.Client
+	tracer        trace.Tracer
+	// baggageKeys are offered in the forms, see withBaggage.
+	baggageKeys []string
+}
+
+// pingServer is a health check which fails if service-1 is not reachable.
+// It uses /livez instead of /readyz, so a broken service-2 doesn't cascade up to the ui.
+func (h *handlers) pingServer(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.serverAddress+health.LivezPath, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("service-1 is not alive: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (h *handlers) homeHandler(w http.ResponseWriter, r *http.Request) {
+	err := tmpl.Execute(w, newPage(r.Context(), "", h.baggageKeys))
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// setAPIKey authenticates a request to the API of service-1.
+func (h *handlers) setAPIKey(req *http.Request) {
+	if h.apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, h.apiKey)
+	}
+}
+
+func (h *handlers) setHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "set")
+	defer span.End()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := r.FormValue("key")
+	value := r.FormValue("value")
+
+	// Make POST request to external service
+	query := url.Values{}
+	query.Set("key", key)
+	query.Set("value", value)
+	externalURL := fmt.Sprintf("%s?%s", h.serverAddress, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, externalURL, nil)
+	if err != nil {
+		lib.RecordError(span, err)
+		http.Error(w, "Failed to create POST request", http.StatusInternalServerError)
+		return
+	}
+	lib.SetRequestID(ctx, req.Header)
+	h.setAPIKey(req)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		lib.RecordError(span, err)
+		http.Error(w, "Failed to make POST request", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			http.Error(w, "Failed to close response body", http.StatusInternalServerError)
+			return
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		lib.RecordError(span, err)
+		http.Error(w, "Failed to read response body", http.StatusInternalServerError)
+		return
+	}
+	err = tmpl.Execute(w, newPage(ctx, string(body), h.baggageKeys))
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *handlers) getHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "get")
+	defer span.End()
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+
+	// Make GET request to external service
+	query := url.Values{}
+	query.Set("key", key)
+	externalURL := fmt.Sprintf("%s?%s", h.serverAddress, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, externalURL, nil)
+	if err != nil {
+		lib.RecordError(span, err)
+		http.Error(w, "Failed to create GET request", http.StatusInternalServerError)
+		return
+	}
+	lib.SetRequestID(ctx, req.Header)
+	h.setAPIKey(req)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		lib.RecordError(span, err)
+		http.Error(w, "Failed to make GET request", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			http.Error(w, "Failed to close response body", http.StatusInternalServerError)
+			return
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		lib.RecordError(span, err)
+		http.Error(w, "Failed to read response body", http.StatusInternalServerError)
+		return
+	}
+	err = tmpl.Execute(w, newPage(ctx, string(body), h.baggageKeys))
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		return
+	}
+}
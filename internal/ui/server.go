@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"net/http"
+	"observability-demo/lib"
+	"observability-demo/lib/health"
+	"observability-demo/lib/traceviewer"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// newServer serves the pages, the trace viewer, metrics and health checks.
+// Every page request is written to the access log on log. baggage may be nil.
+func newServer(h *handlers, traceViewer http.Handler, tp trace.TracerProvider, reg *prometheus.Registry, checks *health.Health, admin http.Handler, log *zap.SugaredLogger, baggage *lib.Baggage) http.Handler {
+	mux := http.NewServeMux()
+
+	// handleFunc is a replacement for mux.HandleFunc
+	// which enriches the handler's HTTP instrumentation with the pattern as the http.route.
+	handleFunc := func(pattern string, handlerFunc func(http.ResponseWriter, *http.Request)) {
+		// Configure the "http.route" for the HTTP instrumentation.
+		handler := otelhttp.WithRouteTag(pattern, lib.RequestLogging(log, http.HandlerFunc(handlerFunc)))
+		mux.Handle(pattern, handler)
+	}
+
+	handleFunc("/", h.homeHandler)
+	handleFunc("/set", h.setHandler)
+	handleFunc("/get", h.getHandler)
+	mux.Handle(traceViewerPrefix, traceViewer)
+	mux.Handle(lib.MetricsPath, lib.MetricsHandler(reg))
+	checks.Handle(mux)
+	mux.Handle(lib.AdminPrefix, admin)
+
+	metrics := lib.NewHTTPMetrics(reg, baggage)
+
+	// Add HTTP instrumentation for the whole server.
+	handler := otelhttp.NewHandler(
+		withBaggage(baggage.Keys(), baggage.Middleware(metrics.Middleware(lib.WithTraceResponse(mux)))),
+		"/",
+		otelhttp.WithTracerProvider(tp),
+		otelhttp.WithFilter(isNotTraceViewerRequest),
+		otelhttp.WithFilter(lib.TracedRequest),
+	)
+	return handler
+}
+
+// Service is the ui wired up, ready to be served.
+type Service struct {
+	Handler http.Handler
+	// Receiver accepts OTLP/HTTP spans for the local trace viewer.
+	Receiver http.Handler
+	Traces   *traceviewer.Store
+	Health   *health.Health
+}
+
+func New(cfg Config, tel lib.Telemetry) *Service {
+	h := &handlers{
+		serverAddress: cfg.ServerAddress,
+		apiKey:        cfg.ServerAPIKey,
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport, otelhttp.WithTracerProvider(tel.TracerProvider)),
+		},
+		tracer:      tel.TracerProvider.Tracer("ui"),
+		baggageKeys: tel.Baggage.Keys(),
+	}
+
+	traces := traceviewer.NewStore(cfg.TraceViewerCapacity)
+	receiver := http.NewServeMux()
+	receiver.Handle("/v1/traces", traceviewer.NewReceiver(traces))
+
+	checks := health.New()
+	checks.Register("service-1", h.pingServer)
+	checks.Register("trace-exporter", lib.CheckTraceExporter, health.Informational())
+	tel.Registry.MustRegister(checks)
+
+	admin := http.NewServeMux()
+	if levels := lib.LogLevelsOf(tel.Logger); levels != nil {
+		levels.Handle(admin, cfg.AdminToken)
+	}
+
+	return &Service{
+		Handler:  newServer(h, traceviewer.NewHandler(traces, traceViewerPrefix), tel.TracerProvider, tel.Registry, checks, admin, lib.CreateChildLogger(tel.Logger, "http-server"), tel.Baggage),
+		Receiver: receiver,
+		Traces:   traces,
+		Health:   checks,
+	}
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"observability-demo/lib"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	grafanaURL      = lib.GetEnv("GRAFANA_URL", "http://localhost:3000")
+	tempoDatasource = lib.GetEnv("GRAFANA_TEMPO_DATASOURCE", "tempo")
+)
+
+// page is the data rendered by the ui template.
+type page struct {
+	Response string
+	TraceID  string
+	TraceURL string
+	Baggage  []baggageField
+}
+
+// baggageField is a form field which sets a baggage member, it keeps the value of the current request.
+type baggageField struct {
+	Key   string
+	Value string
+}
+
+func newPage(ctx context.Context, response string, baggageKeys []string) page {
+	p := page{Response: response}
+
+	bag := baggage.FromContext(ctx)
+	for _, key := range baggageKeys {
+		p.Baggage = append(p.Baggage, baggageField{Key: key, Value: bag.Member(key).Value()})
+	}
+
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if spanCtx.IsValid() {
+		p.TraceID = spanCtx.TraceID().String()
+		p.TraceURL = traceURL(p.TraceID)
+	}
+
+	return p
+}
+
+// traceURL returns a link which opens the trace in Grafana Explore using the Tempo datasource.
+func traceURL(traceID string) string {
+	datasource := map[string]string{"type": "tempo", "uid": tempoDatasource}
+	panes := map[string]any{
+		"trace": map[string]any{
+			"datasource": tempoDatasource,
+			"queries": []map[string]any{
+				{
+					"refId":      "A",
+					"datasource": datasource,
+					"queryType":  "traceql",
+					"query":      traceID,
+				},
+			},
+			"range": map[string]string{"from": "now-1h", "to": "now"},
+		},
+	}
+
+	encoded, err := json.Marshal(panes)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/explore?schemaVersion=1&panes=%s", grafanaURL, url.QueryEscape(string(encoded)))
+}
@@ -0,0 +1,14 @@
+package ui
+
+import (
+	"net/http"
+	"strings"
+)
+
+const traceViewerPrefix = "/traces/"
+
+// isNotTraceViewerRequest excludes the trace viewer from the HTTP instrumentation,
+// otherwise browsing traces would create new traces.
+func isNotTraceViewerRequest(r *http.Request) bool {
+	return !strings.HasPrefix(r.URL.Path, traceViewerPrefix)
+}
@@ -0,0 +1,180 @@
+// Package admin serves the introspection endpoints of a service on a separate listener:
+// pprof, expvar and runtime statistics, build information, the effective configuration with masked secrets,
+// the tracer settings and the registered routes.
+// The listener has no authentication, bind it to localhost or an internal network.
+package admin
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"observability-demo/lib"
+	"observability-demo/lib/profiling"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	PprofPath   = "/debug/pprof/"
+	VarsPath    = "/debug/vars"
+	RuntimePath = "/debug/runtime"
+	BuildPath   = "/debug/build"
+	ConfigPath  = "/debug/config"
+	TracingPath = "/debug/tracing"
+	RoutesPath  = "/debug/routes"
+)
+
+// startTime is the start of the process, for the uptime.
+var startTime = time.Now()
+
+type Options struct {
+	// Service is the name of the service.
+	Service string
+	// Config are the sections of the effective configuration, they are masked with Mask.
+	Config map[string]any
+	// Routes are the routes served by the service.
+	Routes []string
+	// Profiler serves the stored profiles if it's not nil.
+	Profiler *profiling.Profiler
+}
+
+// NewHandler serves the admin endpoints, the index on / lists them.
+func NewHandler(opts Options) http.Handler {
+	publishVars()
+
+	masked := make(map[string]any, len(opts.Config))
+	for section, config := range opts.Config {
+		masked[section] = Mask(config)
+	}
+	routes := slices.Clone(opts.Routes)
+	sort.Strings(routes)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PprofPath, pprof.Index)
+	mux.HandleFunc(PprofPath+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(PprofPath+"profile", pprof.Profile)
+	mux.HandleFunc(PprofPath+"symbol", pprof.Symbol)
+	mux.HandleFunc(PprofPath+"trace", pprof.Trace)
+	mux.Handle(VarsPath, expvar.Handler())
+	mux.HandleFunc(RuntimePath, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, ReadRuntimeStats())
+	})
+	mux.HandleFunc(BuildPath, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, ReadBuildInfo())
+	})
+	mux.HandleFunc(ConfigPath, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, masked)
+	})
+	mux.HandleFunc(TracingPath, func(w http.ResponseWriter, _ *http.Request) {
+		settings, ok := lib.CurrentTracingSettings()
+		if !ok {
+			http.Error(w, "tracing is not set up", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, settings)
+	})
+	mux.HandleFunc(RoutesPath, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, routes)
+	})
+	opts.Profiler.Handle(mux)
+
+	paths := []string{PprofPath, VarsPath, RuntimePath, BuildPath, ConfigPath, TracingPath, RoutesPath}
+	if opts.Profiler != nil {
+		paths = append(paths, profiling.ProfilesPath)
+	}
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = fmt.Fprintf(w, "%s %s\n\n", opts.Service, Version)
+		for _, path := range paths {
+			_, _ = fmt.Fprintln(w, path)
+		}
+	})
+
+	return mux
+}
+
+// GRPCRoutes returns the methods of the services registered on srv, e.g. "gRPC /kv.KV/Get".
+func GRPCRoutes(srv *grpc.Server) []string {
+	var routes []string
+	for service, info := range srv.GetServiceInfo() {
+		for _, method := range info.Methods {
+			routes = append(routes, fmt.Sprintf("gRPC /%s/%s", service, method.Name))
+		}
+	}
+
+	return routes
+}
+
+type RuntimeStats struct {
+	GoVersion  string    `json:"go_version"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	NumCPU     int       `json:"num_cpu"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Goroutines int       `json:"goroutines"`
+	StartTime  time.Time `json:"start_time"`
+	Uptime     string    `json:"uptime"`
+
+	HeapAllocBytes uint64    `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64    `json:"heap_inuse_bytes"`
+	HeapObjects    uint64    `json:"heap_objects"`
+	SysBytes       uint64    `json:"sys_bytes"`
+	NumGC          uint32    `json:"num_gc"`
+	GCPauseTotal   string    `json:"gc_pause_total"`
+	LastGC         time.Time `json:"last_gc,omitzero"`
+	GCCPUFraction  float64   `json:"gc_cpu_fraction"`
+}
+
+// ReadRuntimeStats reads the memory statistics, which briefly stops the world.
+func ReadRuntimeStats() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		GoVersion:      runtime.Version(),
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		NumCPU:         runtime.NumCPU(),
+		GOMAXPROCS:     runtime.GOMAXPROCS(0),
+		Goroutines:     runtime.NumGoroutine(),
+		StartTime:      startTime,
+		Uptime:         time.Since(startTime).Round(time.Second).String(),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapInuseBytes: mem.HeapInuse,
+		HeapObjects:    mem.HeapObjects,
+		SysBytes:       mem.Sys,
+		NumGC:          mem.NumGC,
+		GCPauseTotal:   time.Duration(mem.PauseTotalNs).String(),
+		GCCPUFraction:  mem.GCCPUFraction,
+	}
+	if mem.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+
+	return stats
+}
+
+// publishVars adds the build information and runtime statistics to expvar next to cmdline and memstats.
+// expvar is global, so they are only published once per process.
+var publishVars = sync.OnceFunc(func() {
+	expvar.Publish("build", expvar.Func(func() any { return ReadBuildInfo() }))
+	expvar.Publish("runtime", expvar.Func(func() any { return ReadRuntimeStats() }))
+})
+
+func writeJSON(w http.ResponseWriter, v any) {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"runtime/debug"
+)
+
+// Version, Commit and Date are set at build time, e.g.:
+//
+//	go build -ldflags "-X observability-demo/lib/admin.Version=v1.2.0 -X observability-demo/lib/admin.Commit=$(git rev-parse HEAD)"
+//
+// Without them the commit and date are taken from the VCS information Go embeds into the binary.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+type Module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module,omitempty"`
+	// Settings are the build flags like GOOS, GOARCH, CGO_ENABLED and -ldflags.
+	Settings map[string]string `json:"settings,omitempty"`
+	Deps     []Module          `json:"deps,omitempty"`
+}
+
+// ReadBuildInfo combines the variables set by -ldflags with the information embedded by the Go toolchain.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version: Version,
+		Commit:  Commit,
+		Date:    Date,
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	info.GoVersion = build.GoVersion
+	info.Module = build.Main.Path
+	info.Settings = make(map[string]string, len(build.Settings))
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		default:
+			info.Settings[setting.Key] = setting.Value
+		}
+	}
+	for _, dep := range build.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		info.Deps = append(info.Deps, Module{Path: dep.Path, Version: dep.Version})
+	}
+
+	return info
+}
@@ -0,0 +1,115 @@
+package admin
+
+import (
+	"fmt"
+	"observability-demo/lib/redact"
+	"reflect"
+	"strings"
+)
+
+// secretTag marks a configuration field whose value must not be served, e.g. `admin:"secret"`.
+const secretTag = "secret"
+
+// secretNames are parts of field names which are masked even without tag.
+var secretNames = []string{"token", "secret", "password"}
+
+// Mask converts a configuration into values which can be written as JSON and replaces the secrets by
+// [REDACTED]: the fields tagged with `admin:"secret"` and the fields whose name contains token, secret or password.
+// Unset secrets stay empty, so it's still visible whether they are configured.
+func Mask(config any) any {
+	return mask(reflect.ValueOf(config))
+}
+
+var stringerType = reflect.TypeFor[fmt.Stringer]()
+
+func mask(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	// Durations, levels and regular expressions are more readable as strings.
+	if v.Kind() != reflect.Struct && v.Type().Implements(stringerType) {
+		if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil
+		}
+		return v.Interface().(fmt.Stringer).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return mask(v.Elem())
+	case reflect.Struct:
+		fields := make(map[string]any)
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+				name = tag
+			}
+			if isSecret(field) {
+				fields[name] = maskSecret(v.Field(i))
+				continue
+			}
+			fields[name] = mask(v.Field(i))
+		}
+		return fields
+	case reflect.Map:
+		entries := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries[fmt.Sprint(iter.Key().Interface())] = mask(iter.Value())
+		}
+		return entries
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return []any{}
+		}
+		items := make([]any, v.Len())
+		for i := range v.Len() {
+			items[i] = mask(v.Index(i))
+		}
+		return items
+	case reflect.Func, reflect.Chan:
+		return nil
+	default:
+		return v.Interface()
+	}
+}
+
+func isSecret(field reflect.StructField) bool {
+	if field.Tag.Get("admin") == secretTag {
+		return true
+	}
+
+	name := strings.ToLower(field.Name)
+	for _, secret := range secretNames {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maskSecret keeps the shape of a secret: maps keep their size, but neither keys nor values are shown.
+func maskSecret(v reflect.Value) any {
+	if v.IsZero() {
+		return ""
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		masked := make([]string, v.Len())
+		for i := range masked {
+			masked[i] = redact.Redacted
+		}
+		return masked
+	default:
+		return redact.Redacted
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"fmt"
+	"observability-demo/lib"
+	"slices"
+	"strings"
+)
+
+const (
+	ActionRead   = "read"
+	ActionWrite  = "write"
+	ActionDelete = "delete"
+
+	// AnySubject matches every authenticated caller.
+	AnySubject = "*"
+	// AnyTenant matches the namespaces of all tenants.
+	AnyTenant = "*"
+)
+
+// Rule allows a subject the actions on all keys with the prefix in the namespace of the tenant.
+// The tenant of a request is chosen by the caller, so the rule is what binds a subject to its tenants.
+// The operations on a whole namespace, like deleting it, need a rule without prefix.
+type Rule struct {
+	Subject string   `json:"subject"`
+	Tenant  string   `json:"tenant"`
+	Actions []string `json:"actions"`
+	Prefix  string   `json:"prefix"`
+}
+
+// ACL allows an action if any of its rules allows it, everything else is denied.
+type ACL []Rule
+
+// Allowed reports whether subject may perform action on key in the namespace of tenant,
+// an empty key stands for the whole namespace.
+func (a ACL) Allowed(subject, tenant, action, key string) bool {
+	for _, rule := range a {
+		if rule.Subject != AnySubject && rule.Subject != subject {
+			continue
+		}
+		if rule.Tenant != AnyTenant && rule.Tenant != tenant {
+			continue
+		}
+		if !slices.Contains(rule.Actions, action) {
+			continue
+		}
+		if rule.Prefix == "" || (key != "" && strings.HasPrefix(key, rule.Prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseACL parses a comma separated list of subject:tenant:actions:prefix rules, the actions are separated by |,
+// e.g. alice:acme:read|write:users/alice/,*:*:read:public/.
+func ParseACL(s string) (ACL, error) {
+	var acl ACL
+	for _, item := range lib.SplitList(s) {
+		parts := strings.SplitN(item, ":", 4)
+		if len(parts) != 4 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%q is not subject:tenant:actions:prefix", item)
+		}
+		actions := strings.Split(parts[2], "|")
+		for _, action := range actions {
+			switch action {
+			case ActionRead, ActionWrite, ActionDelete:
+			default:
+				return nil, fmt.Errorf("unknown action %q in %q", action, item)
+			}
+		}
+		acl = append(acl, Rule{Subject: parts[0], Tenant: parts[1], Actions: actions, Prefix: parts[3]})
+	}
+
+	return acl, nil
+}
@@ -0,0 +1,277 @@
+// Package auth authenticates the callers of the key-value API with API keys, HMAC-signed requests or JWTs,
+// authorizes their operations with key prefix ACLs and authenticates the calls between the services.
+// Every decision is recorded on the span, counted and written to the audit log.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"observability-demo/lib"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	MethodAPIKey = "api-key"
+	MethodHMAC   = "hmac"
+	MethodJWT    = "jwt"
+	// MethodService is a call of another service with the service token.
+	MethodService = "service"
+
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+)
+
+const (
+	// MethodKey is the method which authenticated the caller.
+	MethodKey = attribute.Key("auth.method")
+	// SubjectKey is the authenticated caller.
+	SubjectKey = attribute.Key("auth.subject")
+	// ActionKey is the action which was authorized.
+	ActionKey = attribute.Key("auth.action")
+	// DecisionKey is allow or deny.
+	DecisionKey = attribute.Key("auth.decision")
+	// ReasonKey tells why a request was denied.
+	ReasonKey = attribute.Key("auth.reason")
+)
+
+var (
+	// ErrNoCredentials is returned by a verifier if the request carries none of its credentials.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned for credentials which can't be verified.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrForbidden is returned if the ACL doesn't allow an action.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string
+	// Method is the method which authenticated the caller, e.g. api-key.
+	Method string
+}
+
+type principalContextKey struct{}
+
+// WithPrincipal stores the caller of the current request in ctx.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, p)
+}
+
+// PrincipalFrom returns the caller of the current request, if it was authenticated.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalContextKey{}).(Principal)
+	return p, ok
+}
+
+type Config struct {
+	// APIKeys maps the accepted API keys to their subject.
+	APIKeys map[string]string `admin:"secret"`
+	// HMACKeys maps the key ids of signed requests to their secret, the key id is the subject.
+	HMACKeys map[string]string `admin:"secret"`
+	// JWKSFile is a local JWKS file with the public keys of the JWT issuer.
+	JWKSFile string
+	// JWTIssuer and JWTAudience are checked against the claims of the tokens if they are set.
+	JWTIssuer   string
+	JWTAudience string
+	ACL         ACL
+	// ServiceToken authenticates service-1 at service-2, both have to use the same token.
+	ServiceToken string
+}
+
+// LoadConfig reads the configuration from the environment:
+// AUTH_API_KEYS and AUTH_HMAC_KEYS are comma separated lists of key=subject and key id=secret,
+// AUTH_JWKS_FILE, AUTH_JWT_ISSUER and AUTH_JWT_AUDIENCE configure the JWTs, AUTH_ACL is parsed by ParseACL
+// and SERVICE_TOKEN is the token between the services.
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		JWKSFile:     lib.GetEnv("AUTH_JWKS_FILE", ""),
+		JWTIssuer:    lib.GetEnv("AUTH_JWT_ISSUER", ""),
+		JWTAudience:  lib.GetEnv("AUTH_JWT_AUDIENCE", ""),
+		ServiceToken: lib.GetEnv("SERVICE_TOKEN", ""),
+	}
+
+	var err error
+	if cfg.APIKeys, err = parsePairs(lib.GetEnv("AUTH_API_KEYS", "")); err != nil {
+		return Config{}, fmt.Errorf("invalid AUTH_API_KEYS: %w", err)
+	}
+	if cfg.HMACKeys, err = parsePairs(lib.GetEnv("AUTH_HMAC_KEYS", "")); err != nil {
+		return Config{}, fmt.Errorf("invalid AUTH_HMAC_KEYS: %w", err)
+	}
+	// Without ACL every action is denied, an authenticated caller has no access to any tenant until a rule grants it.
+	if cfg.ACL, err = ParseACL(lib.GetEnv("AUTH_ACL", "")); err != nil {
+		return Config{}, fmt.Errorf("invalid AUTH_ACL: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Enabled reports whether any authentication method is configured, otherwise the API is open.
+func (c Config) Enabled() bool {
+	return len(c.APIKeys) > 0 || len(c.HMACKeys) > 0 || c.JWKSFile != ""
+}
+
+func parsePairs(s string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, item := range lib.SplitList(s) {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("%q is not key=value", item)
+		}
+		pairs[key] = value
+	}
+
+	return pairs, nil
+}
+
+// verifier checks one kind of credentials. It returns ErrNoCredentials if the request doesn't carry them,
+// so the next verifier is tried.
+type verifier interface {
+	Method() string
+	Verify(r *http.Request) (Principal, error)
+}
+
+// Authenticator authenticates the requests to the API and authorizes the actions of the callers.
+// A nil Authenticator lets every request through.
+type Authenticator struct {
+	verifiers []verifier
+	acl       ACL
+
+	audit           *zap.SugaredLogger
+	authentications *prometheus.CounterVec
+	authorizations  *prometheus.CounterVec
+}
+
+// New creates the verifiers of the configured methods and registers the metrics on reg.
+// The decisions are written to audit.
+func New(cfg Config, reg prometheus.Registerer, audit *zap.SugaredLogger) (*Authenticator, error) {
+	a := &Authenticator{
+		acl:   cfg.ACL,
+		audit: audit,
+		authentications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_authentications_total",
+			Help: "Authentication attempts by method and decision.",
+		}, []string{"method", "decision"}),
+		authorizations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_authorizations_total",
+			Help: "Authorization decisions by action.",
+		}, []string{"action", "decision"}),
+	}
+
+	if len(cfg.APIKeys) > 0 {
+		a.verifiers = append(a.verifiers, apiKeys(cfg.APIKeys))
+	}
+	if len(cfg.HMACKeys) > 0 {
+		a.verifiers = append(a.verifiers, hmacKeys{secrets: cfg.HMACKeys, now: time.Now})
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", cfg.JWKSFile, err)
+		}
+		a.verifiers = append(a.verifiers, &jwtVerifier{keys: keys, issuer: cfg.JWTIssuer, audience: cfg.JWTAudience, now: time.Now})
+	}
+	if len(a.verifiers) == 0 {
+		return nil, errors.New("no authentication method is configured")
+	}
+
+	reg.MustRegister(a.authentications, a.authorizations)
+
+	return a, nil
+}
+
+// Middleware rejects requests without valid credentials with 401 and stores the caller in the context.
+// It has to be wrapped by lib.RequestLogging, so the audit log carries the fields of the request.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+
+		principal, method, err := a.authenticate(r)
+		if err != nil {
+			span.SetAttributes(MethodKey.String(method), DecisionKey.String(DecisionDeny), ReasonKey.String(err.Error()))
+			lib.IncWithExemplar(ctx, a.authentications.WithLabelValues(method, DecisionDeny))
+			lib.ContextLogger(ctx, a.audit).Warnw("authentication failed", "auth_method", method, "reason", err.Error())
+
+			w.Header().Set("WWW-Authenticate", a.challenge())
+			lib.HTTPError(w, span, http.StatusUnauthorized, err)
+			return
+		}
+
+		span.SetAttributes(MethodKey.String(principal.Method), SubjectKey.String(principal.Subject))
+		lib.IncWithExemplar(ctx, a.authentications.WithLabelValues(principal.Method, DecisionAllow))
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(ctx, principal)))
+	})
+}
+
+// authenticate tries the verifiers in order. The method is "none" if the request carries no credentials.
+func (a *Authenticator) authenticate(r *http.Request) (Principal, string, error) {
+	for _, v := range a.verifiers {
+		principal, err := v.Verify(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return principal, v.Method(), err
+	}
+
+	return Principal{}, "none", ErrNoCredentials
+}
+
+// challenge lists the supported schemes for the WWW-Authenticate header.
+func (a *Authenticator) challenge() string {
+	schemes := make([]string, 0, len(a.verifiers))
+	for _, v := range a.verifiers {
+		switch v.Method() {
+		case MethodAPIKey:
+			schemes = append(schemes, "ApiKey header="+APIKeyHeader)
+		case MethodHMAC:
+			schemes = append(schemes, HMACScheme)
+		case MethodJWT:
+			schemes = append(schemes, "Bearer")
+		}
+	}
+
+	return strings.Join(schemes, ", ")
+}
+
+// Authorize checks whether the caller of the current request may perform action on key in the namespace
+// of the request's tenant, an empty key stands for the whole namespace. It returns an error wrapping ErrForbidden if not.
+func (a *Authenticator) Authorize(ctx context.Context, action, key string) error {
+	if a == nil {
+		return nil
+	}
+
+	span := trace.SpanFromContext(ctx)
+	principal, ok := PrincipalFrom(ctx)
+	tenant := lib.Tenant(ctx)
+	decision := DecisionDeny
+	if ok && a.acl.Allowed(principal.Subject, tenant, action, key) {
+		decision = DecisionAllow
+	}
+
+	span.SetAttributes(ActionKey.String(action), DecisionKey.String(decision))
+	lib.IncWithExemplar(ctx, a.authorizations.WithLabelValues(action, decision))
+	log := lib.ContextLogger(ctx, a.audit).With("subject", principal.Subject, "auth_method", principal.Method, "tenant", tenant, "action", action, "key", key)
+
+	if decision == DecisionDeny {
+		reason := "no rule allows the action"
+		span.SetAttributes(ReasonKey.String(reason))
+		log.Warnw("access denied", "reason", reason)
+		return fmt.Errorf("%w: %s may not %s %q of tenant %s", ErrForbidden, principal.Subject, action, key, tenant)
+	}
+	log.Infow("access granted")
+
+	return nil
+}
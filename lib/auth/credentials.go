@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"observability-demo/lib"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// APIKeyHeader carries a static API key.
+	APIKeyHeader = "X-API-Key"
+	// HMACScheme is the authorization scheme of signed requests: HMAC-SHA256 <key id>:<hex signature>.
+	HMACScheme = "HMAC-SHA256"
+	// TimestampHeader carries the unix time at which a request was signed.
+	TimestampHeader = "X-Auth-Timestamp"
+	// MaxClockSkew is the maximum age of a signed request, older requests are rejected to limit replays.
+	MaxClockSkew = 5 * time.Minute
+	// MaxSignedBodySize limits the body which is read to verify a signature before the caller is known.
+	MaxSignedBodySize = 1 << 20
+)
+
+// apiKeys authenticates the callers by the X-API-Key header, the subject is the name of the key.
+type apiKeys map[string]string
+
+func (k apiKeys) Method() string {
+	return MethodAPIKey
+}
+
+func (k apiKeys) Verify(r *http.Request) (Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	// Compare against every key so the time doesn't tell how much of a key matched.
+	var subject string
+	for known, name := range k {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(known)) == 1 {
+			subject = name
+		}
+	}
+	if subject == "" {
+		return Principal{}, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+	}
+
+	return Principal{Subject: subject, Method: MethodAPIKey}, nil
+}
+
+// hmacKeys authenticates signed requests, the subject is the key id. See SignRequest.
+type hmacKeys struct {
+	secrets map[string]string
+	now     func() time.Time
+}
+
+func (k hmacKeys) Method() string {
+	return MethodHMAC
+}
+
+func (k hmacKeys) Verify(r *http.Request) (Principal, error) {
+	credentials, ok := strings.CutPrefix(r.Header.Get("Authorization"), HMACScheme+" ")
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	keyID, signature, ok := strings.Cut(credentials, ":")
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: malformed signature", ErrInvalidCredentials)
+	}
+	secret, ok := k.secrets[keyID]
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: unknown key id %q", ErrInvalidCredentials, keyID)
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: missing or invalid %s", ErrInvalidCredentials, TimestampHeader)
+	}
+	if skew := k.now().Sub(time.Unix(unix, 0)).Abs(); skew > MaxClockSkew {
+		return Principal{}, fmt.Errorf("%w: signature expired", ErrInvalidCredentials)
+	}
+
+	expected, err := signature256(r, secret, timestamp)
+	if err != nil {
+		return Principal{}, err
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, expected) {
+		return Principal{}, fmt.Errorf("%w: signature mismatch", ErrInvalidCredentials)
+	}
+
+	return Principal{Subject: keyID, Method: MethodHMAC}, nil
+}
+
+// SignRequest signs req with the secret of keyID. The signature covers the method, the path with the query,
+// the tenant, the timestamp and the SHA-256 of the body:
+//
+//	HMAC-SHA256(secret, method + "\n" + path?query + "\n" + tenant + "\n" + timestamp + "\n" + hex(sha256(body)))
+//
+// The tenant is resolved like lib.ResolveTenant, from the X-Tenant header or the tenant baggage member,
+// so a captured request can't be replayed against another namespace.
+func SignRequest(req *http.Request, keyID, secret string, now time.Time) error {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature, err := signature256(req, secret, timestamp)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set("Authorization", HMACScheme+" "+keyID+":"+hex.EncodeToString(signature))
+
+	return nil
+}
+
+// signature256 reads the body to hash it and replaces it, so the handler can still read it.
+// Bodies larger than MaxSignedBodySize are rejected.
+func signature256(r *http.Request, secret, timestamp string) ([]byte, error) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(http.MaxBytesReader(nil, r.Body, MaxSignedBodySize)); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, fmt.Errorf("%w: signed body exceeds %d bytes", ErrInvalidCredentials, MaxSignedBodySize)
+			}
+			return nil, fmt.Errorf("failed to read body: %w", err)
+		}
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = io.WriteString(mac, r.Method+"\n"+r.URL.RequestURI()+"\n"+requestTenant(r)+"\n"+timestamp+"\n"+hex.EncodeToString(bodyHash[:]))
+
+	return mac.Sum(nil), nil
+}
+
+// requestTenant is the tenant a request asks for. Invalid tenants are signed as they are, the controller rejects them.
+func requestTenant(r *http.Request) string {
+	tenant, err := lib.ResolveTenant(r, nil)
+	if err != nil {
+		return r.Header.Get(lib.TenantHeader)
+	}
+
+	return tenant
+}
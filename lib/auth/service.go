@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"observability-demo/lib"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ServiceTokenHeader carries the token of the calling service.
+	ServiceTokenHeader   = "X-Service-Token"
+	serviceTokenMetadata = "x-service-token"
+	// SubjectHeader forwards the caller of the calling service, it is only trusted with a valid service token.
+	SubjectHeader   = "X-Auth-Subject"
+	subjectMetadata = "x-auth-subject"
+)
+
+var errInvalidServiceToken = errors.New("missing or invalid service token")
+
+// ServiceAuth authenticates the calls between the services with a shared token.
+// The caller adds the token and the subject of its own caller, the callee rejects calls without the token.
+// A nil ServiceAuth neither adds nor checks the token.
+type ServiceAuth struct {
+	token string
+
+	audit           *zap.SugaredLogger
+	authentications *prometheus.CounterVec
+}
+
+// NewServiceAuth returns nil without token. The rejected calls are written to audit and counted on reg.
+func NewServiceAuth(token string, reg prometheus.Registerer, audit *zap.SugaredLogger) *ServiceAuth {
+	if token == "" {
+		return nil
+	}
+
+	s := &ServiceAuth{
+		token: token,
+		audit: audit,
+		authentications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_service_authentications_total",
+			Help: "Authentication attempts of other services by decision.",
+		}, []string{"decision"}),
+	}
+	reg.MustRegister(s.authentications)
+
+	return s
+}
+
+// Transport adds the token and the caller to the requests sent by base.
+func (s *ServiceAuth) Transport(base http.RoundTripper) http.RoundTripper {
+	if s == nil {
+		return base
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.Header.Set(ServiceTokenHeader, s.token)
+		if p, ok := PrincipalFrom(req.Context()); ok {
+			req.Header.Set(SubjectHeader, p.Subject)
+		}
+
+		return base.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// UnaryClientInterceptor is the gRPC counterpart of Transport.
+func (s *ServiceAuth) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(s.outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor is the gRPC counterpart of Transport for streams.
+func (s *ServiceAuth) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(s.outgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+func (s *ServiceAuth) outgoing(ctx context.Context) context.Context {
+	if s == nil {
+		return ctx
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, serviceTokenMetadata, s.token)
+	if p, ok := PrincipalFrom(ctx); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, subjectMetadata, p.Subject)
+	}
+
+	return ctx
+}
+
+// Middleware rejects requests without the token with 401 and stores the forwarded caller in the context.
+// It has to be wrapped by lib.RequestLogging, so the audit log carries the fields of the request.
+func (s *ServiceAuth) Middleware(next http.Handler) http.Handler {
+	if s == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := s.verify(r.Context(), r.Header.Get(ServiceTokenHeader), r.Header.Get(SubjectHeader))
+		if err != nil {
+			lib.HTTPError(w, trace.SpanFromContext(ctx), http.StatusUnauthorized, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// UnaryServerInterceptor is the gRPC counterpart of Middleware, it has to run after RequestIDUnaryInterceptor.
+// The health service is not protected, so the callers can check the readiness without token.
+func (s *ServiceAuth) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if s == nil || isHealthCheck(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, err := s.verifyIncoming(ctx)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams.
+func (s *ServiceAuth) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if s == nil || isHealthCheck(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx, err := s.verifyIncoming(ss.Context())
+		if err != nil {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func isHealthCheck(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.v1.Health/")
+}
+
+func (s *ServiceAuth) verifyIncoming(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	return s.verify(ctx, first(serviceTokenMetadata), first(subjectMetadata))
+}
+
+// verify checks the token and stores the forwarded subject as the caller.
+func (s *ServiceAuth) verify(ctx context.Context, token, subject string) (context.Context, error) {
+	span := trace.SpanFromContext(ctx)
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		span.SetAttributes(MethodKey.String(MethodService), DecisionKey.String(DecisionDeny), ReasonKey.String(errInvalidServiceToken.Error()))
+		lib.IncWithExemplar(ctx, s.authentications.WithLabelValues(DecisionDeny))
+		lib.ContextLogger(ctx, s.audit).Warnw("service authentication failed", "auth_method", MethodService, "reason", errInvalidServiceToken.Error())
+		return ctx, errInvalidServiceToken
+	}
+
+	span.SetAttributes(MethodKey.String(MethodService))
+	if subject != "" {
+		span.SetAttributes(SubjectKey.String(subject))
+	}
+	lib.IncWithExemplar(ctx, s.authentications.WithLabelValues(DecisionAllow))
+
+	return WithPrincipal(ctx, Principal{Subject: subject, Method: MethodService}), nil
+}
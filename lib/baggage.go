@@ -0,0 +1,173 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const (
+	// BaggageAttributePrefix prefixes the lifted baggage members in span attributes and log fields.
+	BaggageAttributePrefix = "baggage."
+	// OtherBaggageValue replaces the values of metric labels which are not allowed.
+	OtherBaggageValue = "other"
+)
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// BaggageConfig selects the baggage members which are lifted into the telemetry of a service.
+type BaggageConfig struct {
+	// Keys are lifted into span attributes and log fields.
+	Keys []string
+	// MetricLabels are added to the HTTP metrics. Only the allowed values are used as label values,
+	// all others are replaced by OtherBaggageValue to bound the cardinality.
+	MetricLabels []MetricLabel
+}
+
+type MetricLabel struct {
+	Key    string
+	Values []string
+}
+
+// LoadBaggageConfig reads the configuration from the environment:
+// BAGGAGE_KEYS is a comma separated list of members, BAGGAGE_METRIC_LABELS a comma separated list of
+// members with their allowed values like tenant=acme|globex, "none" disables the labels.
+func LoadBaggageConfig() (BaggageConfig, error) {
+	cfg := BaggageConfig{
+		Keys: SplitList(GetEnv("BAGGAGE_KEYS", "tenant,user,experiment")),
+	}
+
+	labels := GetEnv("BAGGAGE_METRIC_LABELS", "tenant=acme|globex|initech,experiment=control|treatment")
+	if labels == "none" {
+		return cfg, nil
+	}
+	for _, label := range SplitList(labels) {
+		key, values, _ := strings.Cut(label, "=")
+		if !labelNamePattern.MatchString(key) || slices.Contains([]string{"route", "method", "code"}, key) {
+			return BaggageConfig{}, fmt.Errorf("invalid BAGGAGE_METRIC_LABELS: %q can't be used as label name", key)
+		}
+		if slices.ContainsFunc(cfg.MetricLabels, func(l MetricLabel) bool { return l.Key == key }) {
+			return BaggageConfig{}, fmt.Errorf("invalid BAGGAGE_METRIC_LABELS: duplicate label %q", key)
+		}
+		cfg.MetricLabels = append(cfg.MetricLabels, MetricLabel{
+			Key:    key,
+			Values: strings.Split(values, "|"),
+		})
+	}
+
+	return cfg, nil
+}
+
+// Baggage lifts the selected members of the W3C baggage into span attributes, log fields and metric labels.
+// A nil Baggage lifts nothing.
+type Baggage struct {
+	cfg BaggageConfig
+}
+
+func NewBaggage(cfg BaggageConfig) *Baggage {
+	return &Baggage{cfg: cfg}
+}
+
+// Keys returns the members which are lifted.
+func (b *Baggage) Keys() []string {
+	if b == nil {
+		return nil
+	}
+
+	return b.cfg.Keys
+}
+
+type baggageContextKey struct{}
+
+// Middleware adds the members to the server span and stores them as log fields for RequestLogging.
+// It has to be wrapped by the otelhttp handler, which extracts the baggage.
+func (b *Baggage) Middleware(next http.Handler) http.Handler {
+	if b == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(b.lift(r.Context())))
+	})
+}
+
+// UnaryServerInterceptor is the gRPC counterpart of Middleware, it has to run before RequestIDUnaryInterceptor.
+func (b *Baggage) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if b == nil {
+			return handler(ctx, req)
+		}
+
+		return handler(b.lift(ctx), req)
+	}
+}
+
+func (b *Baggage) lift(ctx context.Context) context.Context {
+	bag := baggage.FromContext(ctx)
+
+	var attrs []attribute.KeyValue
+	var fields []any
+	for _, key := range b.cfg.Keys {
+		member := bag.Member(key)
+		if member.Key() == "" {
+			continue
+		}
+		attrs = append(attrs, attribute.String(BaggageAttributePrefix+key, member.Value()))
+		fields = append(fields, BaggageAttributePrefix+key, member.Value())
+	}
+	if len(attrs) == 0 {
+		return ctx
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+
+	return context.WithValue(ctx, baggageContextKey{}, fields)
+}
+
+// baggageFields returns the log fields stored by Middleware.
+func baggageFields(ctx context.Context) []any {
+	fields, _ := ctx.Value(baggageContextKey{}).([]any)
+	return fields
+}
+
+// LabelNames returns the names of the metric labels.
+func (b *Baggage) LabelNames() []string {
+	if b == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(b.cfg.MetricLabels))
+	for _, label := range b.cfg.MetricLabels {
+		names = append(names, label.Key)
+	}
+
+	return names
+}
+
+// LabelValues returns the values of the metric labels in the order of LabelNames.
+// A missing member is an empty value, a value which is not allowed is OtherBaggageValue.
+func (b *Baggage) LabelValues(ctx context.Context) []string {
+	if b == nil {
+		return nil
+	}
+
+	bag := baggage.FromContext(ctx)
+	values := make([]string, 0, len(b.cfg.MetricLabels))
+	for _, label := range b.cfg.MetricLabels {
+		value := bag.Member(label.Key).Value()
+		if value != "" && !slices.Contains(label.Values, value) {
+			value = OtherBaggageValue
+		}
+		values = append(values, value)
+	}
+
+	return values
+}
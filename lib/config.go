@@ -0,0 +1,12 @@
+package lib
+
+import "os"
+
+// GetEnv returns the value of the environment variable named by key or fallback if it is unset or empty.
+func GetEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+
+	return fallback
+}
@@ -4,3 +4,18 @@ type Result struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
 }
+
+// Namespace is the usage and quota of a tenant.
+type Namespace struct {
+	Name  string `json:"name"`
+	Keys  int    `json:"keys"`
+	Bytes int    `json:"bytes"`
+	// MaxKeys and MaxBytes are the quota, zero means unlimited.
+	MaxKeys  int `json:"max_keys"`
+	MaxBytes int `json:"max_bytes"`
+}
+
+type DeletedNamespace struct {
+	Name        string `json:"name"`
+	DeletedKeys int    `json:"deleted_keys"`
+}
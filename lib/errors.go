@@ -0,0 +1,6 @@
+package lib
+
+import "errors"
+
+// ErrNotFound is returned when a key does not exist in the store.
+var ErrNotFound = errors.New("not found")
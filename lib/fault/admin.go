@@ -0,0 +1,70 @@
+package fault
+
+import (
+	"encoding/json"
+	"net/http"
+	"observability-demo/lib"
+)
+
+const AdminPath = "/admin/faults"
+
+// Handle registers the admin endpoint on mux, protected by lib.AdminOnly:
+//
+//	GET    /admin/faults       lists the rules
+//	POST   /admin/faults       adds the rule in the body
+//	DELETE /admin/faults       removes all rules
+//	DELETE /admin/faults/{id}  removes a single rule
+func (i *Injector) Handle(mux *http.ServeMux, token string) {
+	mux.Handle("GET "+AdminPath, lib.AdminOnly(token, http.HandlerFunc(i.serveList)))
+	mux.Handle("POST "+AdminPath, lib.AdminOnly(token, http.HandlerFunc(i.serveAdd)))
+	mux.Handle("DELETE "+AdminPath, lib.AdminOnly(token, http.HandlerFunc(i.serveClear)))
+	mux.Handle("DELETE "+AdminPath+"/{id}", lib.AdminOnly(token, http.HandlerFunc(i.serveRemove)))
+}
+
+func (i *Injector) serveList(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, i.Rules())
+}
+
+func (i *Injector) serveAdd(w http.ResponseWriter, r *http.Request) {
+	var rule Rule
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rule); err != nil {
+		http.Error(w, "invalid rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := i.Add(rule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+func (i *Injector) serveClear(w http.ResponseWriter, _ *http.Request) {
+	i.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (i *Injector) serveRemove(w http.ResponseWriter, r *http.Request) {
+	if !i.Remove(r.PathValue("id")) {
+		http.Error(w, "rule not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,319 @@
+// Package fault injects latency, errors, dropped connections and panics into HTTP handlers
+// according to rules which are changed at runtime via an admin endpoint.
+// The gRPC API of service-2 is not affected, faults between the services need STORE_TRANSPORT=http.
+package fault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"observability-demo/lib"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	Fixed       = "fixed"
+	Uniform     = "uniform"
+	Normal      = "normal"
+	Exponential = "exponential"
+)
+
+// Duration is a time.Duration which is written as string in JSON, e.g. "150ms".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// Latency describes the distribution of the injected delay.
+// fixed uses Mean, uniform Min and Max, normal Mean and StdDev and exponential Mean.
+type Latency struct {
+	Distribution string   `json:"distribution"`
+	Mean         Duration `json:"mean,omitempty"`
+	StdDev       Duration `json:"stddev,omitempty"`
+	Min          Duration `json:"min,omitempty"`
+	Max          Duration `json:"max,omitempty"`
+}
+
+func (l Latency) sample() time.Duration {
+	var d float64
+	switch l.Distribution {
+	case Uniform:
+		d = float64(l.Min) + rand.Float64()*float64(l.Max-l.Min)
+	case Normal:
+		d = float64(l.Mean) + rand.NormFloat64()*float64(l.StdDev)
+	case Exponential:
+		d = rand.ExpFloat64() * float64(l.Mean)
+	default:
+		d = float64(l.Mean)
+	}
+
+	return time.Duration(math.Max(d, 0))
+}
+
+func (l Latency) validate() error {
+	switch l.Distribution {
+	case Fixed, Normal, Exponential:
+		if l.Mean <= 0 {
+			return fmt.Errorf("%s latency needs a positive mean", l.Distribution)
+		}
+	case Uniform:
+		if l.Min < 0 || l.Max < l.Min {
+			return errors.New("uniform latency needs 0 <= min <= max")
+		}
+	default:
+		return fmt.Errorf("unknown latency distribution %q", l.Distribution)
+	}
+
+	return nil
+}
+
+// Rule injects faults into the requests matching Route and Method.
+// A request is affected with Probability, the latency is added before the error, drop or panic.
+type Rule struct {
+	ID string `json:"id"`
+	// Route is the ServeMux pattern of the handler, empty matches all routes.
+	Route string `json:"route,omitempty"`
+	// Method is the HTTP method, empty matches all methods.
+	Method string `json:"method,omitempty"`
+	// Probability defaults to 1.
+	Probability float64  `json:"probability,omitempty"`
+	Latency     *Latency `json:"latency,omitempty"`
+	// Status is returned instead of calling the handler.
+	Status int `json:"status,omitempty"`
+	// Drop closes the connection without a response.
+	Drop  bool `json:"drop,omitempty"`
+	Panic bool `json:"panic,omitempty"`
+}
+
+func (r Rule) validate() error {
+	if r.Probability < 0 || r.Probability > 1 {
+		return errors.New("probability has to be between 0 and 1")
+	}
+	if r.Status != 0 && (r.Status < 400 || r.Status > 599) {
+		return errors.New("status has to be an error status code")
+	}
+
+	faults := 0
+	for _, set := range []bool{r.Status != 0, r.Drop, r.Panic} {
+		if set {
+			faults++
+		}
+	}
+	if faults > 1 {
+		return errors.New("only one of status, drop and panic can be set")
+	}
+	if faults == 0 && r.Latency == nil {
+		return errors.New("rule injects no fault")
+	}
+	if r.Latency != nil {
+		return r.Latency.validate()
+	}
+
+	return nil
+}
+
+func (r Rule) matches(req *http.Request) bool {
+	return (r.Route == "" || r.Route == req.Pattern) && (r.Method == "" || r.Method == req.Method)
+}
+
+func (r Rule) kind() string {
+	switch {
+	case r.Status != 0:
+		return "error"
+	case r.Drop:
+		return "drop"
+	case r.Panic:
+		return "panic"
+	default:
+		return "latency"
+	}
+}
+
+// Injector holds the active rules.
+type Injector struct {
+	mu     sync.RWMutex
+	rules  []Rule
+	nextID int
+
+	injected *prometheus.CounterVec
+	log      *zap.SugaredLogger
+}
+
+func NewInjector(reg prometheus.Registerer, log *zap.SugaredLogger) *Injector {
+	i := &Injector{
+		injected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "fault_injections_total",
+			Help: "Total number of requests affected by an injected fault.",
+		}, []string{"route", "type"}),
+		log: log,
+	}
+	reg.MustRegister(i.injected)
+
+	return i
+}
+
+// Rules returns the active rules in the order they are evaluated.
+func (i *Injector) Rules() []Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	rules := make([]Rule, len(i.rules))
+	copy(rules, i.rules)
+
+	return rules
+}
+
+// Add validates the rule, assigns an ID if it has none and appends it.
+func (i *Injector) Add(rule Rule) (Rule, error) {
+	if err := rule.validate(); err != nil {
+		return Rule{}, err
+	}
+	if rule.Probability == 0 {
+		rule.Probability = 1
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if rule.ID == "" {
+		// Skip the IDs which were chosen explicitly.
+		for rule.ID == "" || i.exists(rule.ID) {
+			i.nextID++
+			rule.ID = strconv.Itoa(i.nextID)
+		}
+	} else if i.exists(rule.ID) {
+		return Rule{}, fmt.Errorf("rule %q already exists", rule.ID)
+	}
+	i.rules = append(i.rules, rule)
+	i.log.Warnw("added fault injection rule", "rule", rule.ID, "route", rule.Route, "method", rule.Method, "type", rule.kind())
+
+	return rule, nil
+}
+
+// exists must be called with i.mu held, it tells whether a rule has the ID.
+func (i *Injector) exists(id string) bool {
+	return slices.ContainsFunc(i.rules, func(rule Rule) bool {
+		return rule.ID == id
+	})
+}
+
+// Remove deletes the rule with the ID and reports whether it existed.
+func (i *Injector) Remove(id string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for n, rule := range i.rules {
+		if rule.ID == id {
+			i.rules = append(i.rules[:n], i.rules[n+1:]...)
+			i.log.Infow("removed fault injection rule", "rule", id)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Clear deletes all rules.
+func (i *Injector) Clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules = nil
+	i.log.Infow("removed all fault injection rules")
+}
+
+// pick returns the first matching rule which is hit by its probability.
+func (i *Injector) pick(r *http.Request) (Rule, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for _, rule := range i.rules {
+		if rule.matches(r) && rand.Float64() < rule.Probability {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// Middleware injects the faults of the matching rules.
+// It has to be wrapped by the ServeMux, so the route of the request is known.
+func (i *Injector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule, ok := i.pick(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		span := trace.SpanFromContext(r.Context())
+		span.SetAttributes(
+			attribute.Bool("fault.injected", true),
+			attribute.String("fault.rule_id", rule.ID),
+			attribute.String("fault.type", rule.kind()),
+		)
+		// The ID is chosen by the caller, the routes of the ServeMux keep the label values bounded.
+		lib.IncWithExemplar(r.Context(), i.injected.WithLabelValues(r.Pattern, rule.kind()))
+
+		if rule.Latency != nil {
+			delay := rule.Latency.sample()
+			span.SetAttributes(attribute.Int64("fault.latency_ms", delay.Milliseconds()))
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		switch {
+		case rule.Status != 0:
+			span.SetAttributes(attribute.Int("fault.status_code", rule.Status))
+			http.Error(w, "injected fault", rule.Status)
+		case rule.Drop:
+			span.AddEvent("fault.connection_dropped")
+			drop(w)
+		case rule.Panic:
+			span.AddEvent("fault.panic")
+			panic(fmt.Sprintf("injected panic by fault rule %s", rule.ID))
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// drop closes the connection without writing a response.
+func drop(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		// HTTP/2 connections can't be hijacked, aborting the handler resets the stream instead.
+		panic(http.ErrAbortHandler)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,69 @@
+package fault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+func TestAddSkipsTakenIDs(t *testing.T) {
+	i := NewInjector(prometheus.NewRegistry(), zap.NewNop().Sugar())
+
+	if _, err := i.Add(Rule{ID: "1", Drop: true}); err != nil {
+		t.Fatal(err)
+	}
+	rule, err := i.Add(Rule{Drop: true})
+	if err != nil {
+		t.Fatalf("expected the generated ID to skip the taken one, got %v", err)
+	}
+	if rule.ID != "2" {
+		t.Fatalf("expected ID 2, got %q", rule.ID)
+	}
+	if _, err := i.Add(Rule{ID: "2", Drop: true}); err == nil {
+		t.Fatal("expected an explicit taken ID to be rejected")
+	}
+}
+
+func TestInjectionsAreCountedByRoute(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	i := NewInjector(reg, zap.NewNop().Sugar())
+	for _, id := range []string{"a", "b"} {
+		if _, err := i.Add(Rule{ID: id, Route: "/", Status: http.StatusServiceUnavailable}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", i.Middleware(http.NotFoundHandler()))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "fault_injections_total" {
+			continue
+		}
+		// The rule ID is no label, so the series doesn't depend on which rule was hit.
+		if len(family.GetMetric()) != 1 {
+			t.Fatalf("expected a single series, got %v", family.GetMetric())
+		}
+		labels := make(map[string]string)
+		for _, label := range family.GetMetric()[0].GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		if len(labels) != 2 || labels["route"] != "/" || labels["type"] != "error" {
+			t.Fatalf("expected the labels route=/ and type=error, got %v", labels)
+		}
+		return
+	}
+	t.Fatal("fault_injections_total was not gathered")
+}
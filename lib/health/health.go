@@ -0,0 +1,267 @@
+// Package health runs the health checks registered by the components of a service
+// and serves them on /livez, /readyz and /healthz.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	LivezPath   = "/livez"
+	ReadyzPath  = "/readyz"
+	HealthzPath = "/healthz"
+
+	defaultTimeout  = time.Second
+	defaultCacheTTL = 5 * time.Second
+)
+
+// CheckFunc returns an error if the checked dependency is not healthy.
+type CheckFunc func(ctx context.Context) error
+
+type Option func(*check)
+
+// WithTimeout limits how long a single run of the check may take.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// WithCacheTTL sets how long the result of the check is reused.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *check) {
+		c.cacheTTL = ttl
+	}
+}
+
+// Liveness makes a failing check fail /livez, which should lead to a restart of the service.
+func Liveness() Option {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// Informational keeps a failing check from failing /readyz, it is only reported on /healthz.
+func Informational() Option {
+	return func(c *check) {
+		c.informational = true
+	}
+}
+
+type check struct {
+	name          string
+	fn            CheckFunc
+	timeout       time.Duration
+	cacheTTL      time.Duration
+	liveness      bool
+	informational bool
+
+	// mu serializes runs of the check, so concurrent probes share one result.
+	mu     sync.Mutex
+	result Result
+}
+
+// Result is the outcome of the last run of a check.
+type Result struct {
+	Name      string        `json:"name"`
+	Healthy   bool          `json:"healthy"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"-"`
+	Took      string        `json:"duration"`
+	CheckedAt time.Time     `json:"checked_at"`
+	// Critical checks fail /readyz.
+	Critical bool `json:"critical"`
+}
+
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < c.cacheTTL {
+		return c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = errors.New("check timed out")
+	}
+
+	duration := time.Since(start)
+	c.result = Result{
+		Name:      c.name,
+		Healthy:   err == nil,
+		Duration:  duration,
+		Took:      duration.String(),
+		CheckedAt: start,
+		Critical:  !c.informational,
+	}
+	if err != nil {
+		c.result.Error = err.Error()
+	}
+
+	return c.result
+}
+
+// Health holds the registered checks of a service.
+type Health struct {
+	mu     sync.RWMutex
+	checks map[string]*check
+
+	status   *prometheus.Desc
+	duration *prometheus.Desc
+}
+
+func New() *Health {
+	return &Health{
+		checks: make(map[string]*check),
+		status: prometheus.NewDesc(
+			"health_check_status", "Result of the last health check, 1 if healthy.", []string{"check"}, nil,
+		),
+		duration: prometheus.NewDesc(
+			"health_check_duration_seconds", "Duration of the last health check.", []string{"check"}, nil,
+		),
+	}
+}
+
+// Register adds a check, a check with the same name is replaced.
+func (h *Health) Register(name string, fn CheckFunc, opts ...Option) {
+	c := &check{
+		name:     name,
+		fn:       fn,
+		timeout:  defaultTimeout,
+		cacheTTL: defaultCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	h.mu.Lock()
+	h.checks[name] = c
+	h.mu.Unlock()
+}
+
+// Run runs all checks in parallel, or returns their cached result, sorted by name.
+func (h *Health) Run(ctx context.Context) []Result {
+	h.mu.RLock()
+	checks := make([]*check, 0, len(h.checks))
+	for _, c := range h.checks {
+		checks = append(checks, c)
+	}
+	h.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	return results
+}
+
+func (h *Health) isLiveness(name string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	c, ok := h.checks[name]
+	return ok && c.liveness
+}
+
+// Handle registers /livez, /readyz and /healthz on mux.
+func (h *Health) Handle(mux *http.ServeMux) {
+	mux.HandleFunc(LivezPath, h.serveLivez)
+	mux.HandleFunc(ReadyzPath, h.serveReadyz)
+	mux.HandleFunc(HealthzPath, h.serveHealthz)
+}
+
+// serveLivez fails only if a liveness check fails, a broken dependency must not restart the service.
+func (h *Health) serveLivez(w http.ResponseWriter, r *http.Request) {
+	for _, result := range h.Run(r.Context()) {
+		if !result.Healthy && h.isLiveness(result.Name) {
+			http.Error(w, result.Name+": "+result.Error, http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	_, _ = w.Write([]byte("ok"))
+}
+
+// serveReadyz fails if any critical check fails.
+func (h *Health) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	for _, result := range h.Run(r.Context()) {
+		if !result.Healthy && result.Critical {
+			http.Error(w, result.Name+": "+result.Error, http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	_, _ = w.Write([]byte("ok"))
+}
+
+// serveHealthz reports the result of every check as JSON.
+func (h *Health) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	results := h.Run(r.Context())
+
+	healthy := true
+	for _, result := range results {
+		if !result.Healthy && result.Critical {
+			healthy = false
+		}
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"healthy": healthy,
+		"checks":  results,
+	})
+	if err != nil {
+		http.Error(w, "failed to marshal results", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(body)
+}
+
+func (h *Health) Describe(ch chan<- *prometheus.Desc) {
+	ch <- h.status
+	ch <- h.duration
+}
+
+// Collect runs the checks, so the metrics are at most as old as the cache TTL.
+func (h *Health) Collect(ch chan<- prometheus.Metric) {
+	for _, result := range h.Run(context.Background()) {
+		status := 0.0
+		if result.Healthy {
+			status = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(h.status, prometheus.GaugeValue, status, result.Name)
+		ch <- prometheus.MustNewConstMetric(h.duration, prometheus.GaugeValue, result.Duration.Seconds(), result.Name)
+	}
+}
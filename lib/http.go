@@ -0,0 +1,28 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TraceResponseHeader = "traceresponse"
+	TraceIDHeader       = "X-Trace-Id"
+)
+
+// WithTraceResponse returns the trace ID of the current request to the caller
+// as traceresponse and X-Trace-Id headers.
+// It has to be wrapped by the otelhttp handler so that the server span is already started.
+func WithTraceResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spanCtx := trace.SpanContextFromContext(r.Context())
+		if spanCtx.IsValid() {
+			w.Header().Set(TraceResponseHeader, fmt.Sprintf("00-%s-%s-%s", spanCtx.TraceID(), spanCtx.SpanID(), spanCtx.TraceFlags()))
+			w.Header().Set(TraceIDHeader, spanCtx.TraceID().String())
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
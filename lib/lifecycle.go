@@ -0,0 +1,225 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+type lifecycleServer struct {
+	name  string
+	serve func() error
+	stop  func(context.Context) error
+}
+
+type lifecycleHook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// Lifecycle runs the servers of a service until SIGINT or SIGTERM and then shuts down in order:
+// first all servers are drained, then the shutdown hooks run in the order they were added
+// and finally the logger is synced. Everything has to finish within the shutdown timeout,
+// a quarter of it is reserved for the hooks so a slow drain can't keep them from flushing the telemetry.
+type Lifecycle struct {
+	ctx     context.Context
+	stop    context.CancelFunc
+	timeout time.Duration
+
+	servers      []lifecycleServer
+	hooks        []lifecycleHook
+	shuttingDown atomic.Bool
+
+	logger *zap.Logger
+	log    *zap.SugaredLogger
+}
+
+func NewLifecycle(ctx context.Context, logger *zap.Logger, timeout time.Duration) *Lifecycle {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+
+	return &Lifecycle{
+		ctx:     ctx,
+		stop:    stop,
+		timeout: timeout,
+		logger:  logger,
+		log:     CreateChildLogger(logger, "lifecycle"),
+	}
+}
+
+// Context is cancelled as soon as the shutdown starts.
+// Don't use it as base context of servers, in-flight requests would be cancelled instead of drained.
+func (l *Lifecycle) Context() context.Context {
+	return l.ctx
+}
+
+// AddServer runs serve until the shutdown starts, then stop has to return once the server is drained.
+func (l *Lifecycle) AddServer(name string, serve func() error, stop func(context.Context) error) {
+	l.servers = append(l.servers, lifecycleServer{name: name, serve: serve, stop: stop})
+}
+
+func (l *Lifecycle) AddHTTPServer(name string, srv *http.Server) {
+	l.AddServer(
+		name,
+		func() error {
+			l.log.Infof("%s listening on %s", name, srv.Addr)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+		srv.Shutdown,
+	)
+}
+
+// AddGRPCServer serves on addr and stops gracefully, or forcefully once the shutdown timeout is reached.
+func (l *Lifecycle) AddGRPCServer(name string, srv *grpc.Server, addr string) {
+	l.AddServer(
+		name,
+		func() error {
+			lis, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			l.log.Infof("%s listening on %s", name, lis.Addr())
+			return srv.Serve(lis)
+		},
+		func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(stopped)
+			}()
+
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				srv.Stop()
+				return ctx.Err()
+			}
+		},
+	)
+}
+
+// OnShutdown adds a hook which runs after all servers are drained, e.g. to flush telemetry.
+func (l *Lifecycle) OnShutdown(name string, fn func(context.Context) error) {
+	l.hooks = append(l.hooks, lifecycleHook{name: name, fn: fn})
+}
+
+// CheckRunning is a health check which fails once the shutdown started,
+// so no new traffic is sent while the servers are drained.
+func (l *Lifecycle) CheckRunning(_ context.Context) error {
+	if l.shuttingDown.Load() {
+		return errors.New("shutting down")
+	}
+
+	return nil
+}
+
+// Run starts all servers and blocks until the shutdown is complete.
+// If a server fails, the other servers are shut down and the error is returned.
+func (l *Lifecycle) Run() error {
+	defer l.stop()
+
+	errs := make(chan error, len(l.servers))
+	for _, srv := range l.servers {
+		go func() {
+			if err := srv.serve(); err != nil {
+				errs <- fmt.Errorf("%s failed: %w", srv.name, err)
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-l.ctx.Done():
+		l.log.Info("received signal, shutting down")
+	case runErr = <-errs:
+		l.log.Errorf("shutting down: %s", runErr)
+	}
+
+	return errors.Join(runErr, l.shutdown())
+}
+
+func (l *Lifecycle) shutdown() error {
+	l.shuttingDown.Store(true)
+	start := time.Now()
+
+	deadline := start.Add(l.timeout)
+	reserved := l.timeout / 4
+
+	drainCtx, cancel := context.WithDeadline(context.Background(), deadline.Add(-reserved))
+	defer cancel()
+
+	var errs []error
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, srv := range l.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := srv.stop(drainCtx); err != nil {
+				l.log.Errorf("failed to drain %s: %s", srv.name, err)
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				return
+			}
+			l.log.Infof("drained %s", srv.name)
+		}()
+	}
+	wg.Wait()
+
+	// The hooks get the rest of the timeout, at least the reserved part even if a server ignored its deadline.
+	hooksCtx, cancel := context.WithDeadline(context.Background(), later(deadline, time.Now().Add(reserved)))
+	defer cancel()
+
+	for _, hook := range l.hooks {
+		if err := hook.fn(hooksCtx); err != nil {
+			l.log.Errorf("failed to shut down %s: %s", hook.name, err)
+			errs = append(errs, err)
+			continue
+		}
+		l.log.Infof("shut down %s", hook.name)
+	}
+
+	l.log.Infof("shutdown complete after %s", time.Since(start))
+
+	// Syncing stderr fails on some platforms, there is nothing left to report the error to anyway.
+	_ = l.logger.Sync()
+
+	return errors.Join(errs...)
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+// ShutdownTracerProvider flushes the pending spans before shutting the provider down.
+func ShutdownTracerProvider(tp *sdktrace.TracerProvider) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if err := tp.ForceFlush(ctx); err != nil {
+			return fmt.Errorf("failed to flush spans: %w", err)
+		}
+
+		return tp.Shutdown(ctx)
+	}
+}
@@ -1,26 +1,37 @@
 package lib
 
 import (
+	"fmt"
+	"observability-demo/lib/logsink"
+	"observability-demo/lib/redact"
+	"time"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// CreateProductionLogger logs JSON at info level, the level can be changed at runtime with LogLevelsOf.
+// The entries are redacted, sampled, rate limited and written to the outputs configured by LoadLogConfig.
 func CreateProductionLogger(component string) *zap.Logger {
+	logCfg, err := LoadLogConfig()
+	if err != nil {
+		panic(err)
+	}
+
 	encoderCfg := zap.NewProductionEncoderConfig()
 	encoderCfg.TimeKey = "timestamp"
 	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
 
 	config := zap.Config{
-		Level:             zap.NewAtomicLevelAt(zap.InfoLevel),
+		// The leveledCore filters by the level of the component, the encoder has to accept every level.
+		Level:             zap.NewAtomicLevelAt(zap.DebugLevel),
 		Development:       false,
 		DisableCaller:     false,
 		DisableStacktrace: false,
 		Sampling:          nil,
 		Encoding:          "json",
 		EncoderConfig:     encoderCfg,
-		OutputPaths: []string{
-			"stderr",
-		},
+		OutputPaths:       logCfg.Outputs,
 		ErrorOutputPaths: []string{
 			"stderr",
 		},
@@ -29,13 +40,82 @@ func CreateProductionLogger(component string) *zap.Logger {
 		},
 	}
 
-	return zap.Must(config.Build())
+	var opts []zap.Option
+	redactor, err := LoadRedactor()
+	if err != nil {
+		panic(err)
+	}
+	if redactor != nil {
+		opts = append(opts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return redact.NewCore(c, redactor)
+		}))
+	}
+	opts = append(opts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		c = newLevelSampler(c, logCfg.SamplingTick, logCfg.Sampling)
+		return newRateLimitCore(c, logCfg.RateLimit, logCfg.RateLimitInterval)
+	}))
+
+	levels := newLogLevels(zap.InfoLevel)
+	opts = append(opts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &leveledCore{Core: c, levels: levels}
+	}))
+
+	logger := zap.Must(config.Build(opts...))
+	levels.log = CreateChildLogger(logger, "log-level")
+
+	return logger
+}
+
+// LogConfig configures the outputs, sampling and rate limiting of CreateProductionLogger.
+type LogConfig struct {
+	// Outputs are zap output paths, including the sinks of the logsink package.
+	Outputs      []string
+	Sampling     map[zapcore.Level]SamplingRule
+	SamplingTick time.Duration
+	// RateLimit is the number of entries per message and RateLimitInterval, zero disables rate limiting.
+	RateLimit         int
+	RateLimitInterval time.Duration
+}
+
+// LoadLogConfig reads the configuration of the logger from the environment:
+//
+//	LOG_OUTPUTS         comma separated output paths, default stderr
+//	LOG_SAMPLING        first:thereafter per level and message, default debug=100:100,info=100:100, none disables it
+//	LOG_SAMPLING_TICK   sampling interval, default 1s
+//	LOG_RATE_LIMIT      entries per message and interval, e.g. 10/1s, disabled by default
+func LoadLogConfig() (LogConfig, error) {
+	if err := logsink.Register(); err != nil {
+		return LogConfig{}, err
+	}
+
+	cfg := LogConfig{
+		Outputs: SplitList(GetEnv("LOG_OUTPUTS", "stderr")),
+	}
+
+	var err error
+	if cfg.Sampling, err = parseSampling(GetEnv("LOG_SAMPLING", "debug=100:100,info=100:100")); err != nil {
+		return LogConfig{}, fmt.Errorf("invalid LOG_SAMPLING: %w", err)
+	}
+	if cfg.SamplingTick, err = time.ParseDuration(GetEnv("LOG_SAMPLING_TICK", "1s")); err != nil {
+		return LogConfig{}, fmt.Errorf("invalid LOG_SAMPLING_TICK: %w", err)
+	}
+	if cfg.RateLimit, cfg.RateLimitInterval, err = parseRateLimit(GetEnv("LOG_RATE_LIMIT", "")); err != nil {
+		return LogConfig{}, fmt.Errorf("invalid LOG_RATE_LIMIT: %w", err)
+	}
+
+	return cfg, nil
 }
 
+// CreateChildLogger adds the service field, the level of the child logger can be changed per service.
 func CreateChildLogger(logger *zap.Logger, service string) *zap.SugaredLogger {
 	childLogger := logger.With(
 		zap.String("service", service),
-	)
+	).WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		if leveled, ok := c.(*leveledCore); ok {
+			return leveled.forComponent(service)
+		}
+		return c
+	}))
 
 	return childLogger.Sugar()
 }
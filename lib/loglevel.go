@@ -0,0 +1,317 @@
+package lib
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// AdminPrefix is the prefix of all admin endpoints.
+	AdminPrefix  = "/admin/"
+	LogLevelPath = AdminPrefix + "log-level"
+)
+
+// ErrUnknownComponent is returned for a component without child logger.
+var ErrUnknownComponent = errors.New("unknown log component")
+
+// componentLevel overrides the global level for the child loggers of a component, nil follows the global level.
+type componentLevel struct {
+	override atomic.Pointer[zapcore.Level]
+}
+
+// pendingRevert restores the level a component had before the first of a series of temporary changes.
+type pendingRevert struct {
+	timer   *time.Timer
+	restore *zapcore.Level
+}
+
+// LogLevels holds the global level of a logger created by CreateProductionLogger
+// and the levels of the components of its child loggers, both can be changed at runtime.
+type LogLevels struct {
+	global zap.AtomicLevel
+
+	mu         sync.Mutex
+	components map[string]*componentLevel
+	reverts    map[string]*pendingRevert
+
+	log *zap.SugaredLogger
+}
+
+func newLogLevels(level zapcore.Level) *LogLevels {
+	return &LogLevels{
+		global:     zap.NewAtomicLevelAt(level),
+		components: make(map[string]*componentLevel),
+		reverts:    make(map[string]*pendingRevert),
+	}
+}
+
+// LogLevelsOf returns the levels of a logger created by CreateProductionLogger, or nil for any other logger.
+func LogLevelsOf(logger *zap.Logger) *LogLevels {
+	if c, ok := logger.Core().(*leveledCore); ok {
+		return c.levels
+	}
+
+	return nil
+}
+
+func (l *LogLevels) component(name string) *componentLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.components[name]
+	if !ok {
+		c = &componentLevel{}
+		l.components[name] = c
+	}
+
+	return c
+}
+
+func (l *LogLevels) enabled(c *componentLevel, level zapcore.Level) bool {
+	if c != nil {
+		if override := c.override.Load(); override != nil {
+			return override.Enabled(level)
+		}
+	}
+
+	return l.global.Enabled(level)
+}
+
+// Levels returns the global level and the effective level of every known component.
+func (l *LogLevels) Levels() (string, map[string]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	global := l.global.Level()
+	components := make(map[string]string, len(l.components))
+	for name, c := range l.components {
+		level := global
+		if override := c.override.Load(); override != nil {
+			level = *override
+		}
+		components[name] = level.String()
+	}
+
+	return global.String(), components
+}
+
+// SetLevel changes the level of component, or the global level if component is empty.
+// If revertAfter is positive, the level from before the change is restored after it. A change while another
+// revert is pending keeps the level from before the first change, so the override never becomes permanent.
+// A change without revertAfter is permanent and cancels a pending revert.
+// It fails with ErrUnknownComponent if no child logger of the component exists.
+func (l *LogLevels) SetLevel(component string, level zapcore.Level, revertAfter time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.known(component) {
+		return fmt.Errorf("%w %q", ErrUnknownComponent, component)
+	}
+	l.setLevel(component, level, revertAfter)
+
+	return nil
+}
+
+// setLevel must be called with l.mu held and a known component.
+func (l *LogLevels) setLevel(component string, level zapcore.Level, revertAfter time.Duration) {
+	restore := l.set(component, &level)
+	if pending, ok := l.reverts[component]; ok {
+		pending.timer.Stop()
+		restore = pending.restore
+		delete(l.reverts, component)
+	}
+	if revertAfter > 0 {
+		pending := &pendingRevert{restore: restore}
+		pending.timer = time.AfterFunc(revertAfter, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			// The timer may have fired while a later change replaced or cancelled this revert.
+			if l.reverts[component] != pending {
+				return
+			}
+			delete(l.reverts, component)
+			l.set(component, restore)
+			l.log.Warnw("reverted log level", "log_component", displayName(component))
+		})
+		l.reverts[component] = pending
+	}
+
+	l.log.Warnw("changed log level", "log_component", displayName(component), "level", level.String(), "revert_after", revertAfter.String())
+}
+
+// ResetLevel lets component follow the global level again and cancels a pending revert.
+// It fails with ErrUnknownComponent if no child logger of the component exists.
+func (l *LogLevels) ResetLevel(component string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.known(component) {
+		return fmt.Errorf("%w %q", ErrUnknownComponent, component)
+	}
+
+	if pending, ok := l.reverts[component]; ok {
+		pending.timer.Stop()
+		delete(l.reverts, component)
+	}
+	l.set(component, nil)
+	l.log.Warnw("reset log level", "log_component", displayName(component))
+
+	return nil
+}
+
+// known must be called with l.mu held, it tells whether component is global or has a child logger.
+func (l *LogLevels) known(component string) bool {
+	_, ok := l.components[component]
+	return component == "" || ok
+}
+
+// set must be called with l.mu held and a known component, it returns the previous level. A nil level resets a component.
+func (l *LogLevels) set(component string, level *zapcore.Level) *zapcore.Level {
+	if component == "" {
+		previous := l.global.Level()
+		if level != nil {
+			l.global.SetLevel(*level)
+		}
+		return &previous
+	}
+
+	return l.components[component].override.Swap(level)
+}
+
+func displayName(component string) string {
+	if component == "" {
+		return "global"
+	}
+
+	return component
+}
+
+// leveledCore filters entries by the level of its component instead of the level of the wrapped core.
+type leveledCore struct {
+	zapcore.Core
+
+	levels    *LogLevels
+	component *componentLevel
+}
+
+func (c *leveledCore) Enabled(level zapcore.Level) bool {
+	return c.levels.enabled(c.component, level)
+}
+
+func (c *leveledCore) With(fields []zapcore.Field) zapcore.Core {
+	return &leveledCore{Core: c.Core.With(fields), levels: c.levels, component: c.component}
+}
+
+func (c *leveledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
+
+// forComponent returns a copy of the core which uses the level of component.
+func (c *leveledCore) forComponent(component string) *leveledCore {
+	return &leveledCore{Core: c.Core, levels: c.levels, component: c.levels.component(component)}
+}
+
+// logLevelRequest changes the level of Component, or the global level if it's empty.
+type logLevelRequest struct {
+	Component string `json:"component,omitempty"`
+	Level     string `json:"level"`
+	// RevertAfter restores the previous level after the duration, e.g. "5m".
+	RevertAfter string `json:"revert_after,omitempty"`
+}
+
+// Handle registers the log level endpoint on mux, protected by AdminOnly:
+//
+//	GET    /admin/log-level              returns the global and component levels
+//	PUT    /admin/log-level              changes a level, see logLevelRequest
+//	DELETE /admin/log-level/{component}  lets a component follow the global level again
+func (l *LogLevels) Handle(mux *http.ServeMux, token string) {
+	mux.Handle("GET "+LogLevelPath, AdminOnly(token, http.HandlerFunc(l.serveGet)))
+	mux.Handle("PUT "+LogLevelPath, AdminOnly(token, http.HandlerFunc(l.servePut)))
+	mux.Handle("DELETE "+LogLevelPath+"/{component}", AdminOnly(token, http.HandlerFunc(l.serveDelete)))
+}
+
+func (l *LogLevels) serveGet(w http.ResponseWriter, _ *http.Request) {
+	global, components := l.Levels()
+
+	body, err := json.Marshal(map[string]any{
+		"global":     global,
+		"components": components,
+	})
+	if err != nil {
+		http.Error(w, "failed to marshal levels", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (l *LogLevels) servePut(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var revertAfter time.Duration
+	if req.RevertAfter != "" {
+		if revertAfter, err = time.ParseDuration(req.RevertAfter); err != nil {
+			http.Error(w, "invalid revert_after: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := l.SetLevel(req.Component, level, revertAfter); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	l.serveGet(w, r)
+}
+
+func (l *LogLevels) serveDelete(w http.ResponseWriter, r *http.Request) {
+	if err := l.ResetLevel(r.PathValue("component")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	l.serveGet(w, r)
+}
+
+// AdminOnly protects an admin endpoint with the bearer token. Without a token the endpoint is disabled.
+func AdminOnly(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, "admin endpoints are disabled, set ADMIN_TOKEN", http.StatusForbidden)
+			return
+		}
+
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestLogLevels(t *testing.T, components ...string) *LogLevels {
+	t.Helper()
+
+	levels := newLogLevels(zap.InfoLevel)
+	levels.log = zap.NewNop().Sugar()
+	for _, component := range components {
+		levels.component(component)
+	}
+
+	return levels
+}
+
+func assertLevel(t *testing.T, levels *LogLevels, component, expected string) {
+	t.Helper()
+
+	_, components := levels.Levels()
+	if components[component] != expected {
+		t.Fatalf("expected level %s of %s, got %s", expected, component, components[component])
+	}
+}
+
+func TestSetLevelReplacesPendingRevert(t *testing.T) {
+	levels := newTestLogLevels(t, "store")
+	if err := levels.SetLevel("store", zap.DebugLevel, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first revert fires and waits for the lock while the second change replaces it.
+	levels.mu.Lock()
+	time.Sleep(50 * time.Millisecond)
+	levels.setLevel("store", zap.WarnLevel, time.Hour)
+	levels.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+	assertLevel(t, levels, "store", "warn")
+
+	// The replacing revert restores the level from before the first change.
+	levels.mu.Lock()
+	pending := levels.reverts["store"]
+	levels.mu.Unlock()
+	if pending == nil || pending.restore != nil {
+		t.Fatalf("expected a pending revert to the global level, got %+v", pending)
+	}
+}
+
+func TestResetLevelCancelsPendingRevert(t *testing.T) {
+	levels := newTestLogLevels(t, "store")
+	if err := levels.SetLevel("store", zap.DebugLevel, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	levels.mu.Lock()
+	time.Sleep(50 * time.Millisecond)
+	levels.mu.Unlock()
+	if err := levels.ResetLevel("store"); err != nil {
+		t.Fatal(err)
+	}
+	if err := levels.SetLevel("store", zap.ErrorLevel, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	assertLevel(t, levels, "store", zapcore.ErrorLevel.String())
+}
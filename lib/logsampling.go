@@ -0,0 +1,253 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingRule logs the First entries with the same level and message per tick, after that only every Thereafter-th.
+type SamplingRule struct {
+	First      int
+	Thereafter int
+}
+
+// parseSampling parses rules like "debug=100:100,info=100:10", "none" disables sampling.
+func parseSampling(s string) (map[zapcore.Level]SamplingRule, error) {
+	rules := make(map[zapcore.Level]SamplingRule)
+	if s == "none" {
+		return rules, nil
+	}
+
+	for _, item := range SplitList(s) {
+		name, rule, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid sampling rule %q, expected level=first:thereafter", item)
+		}
+		level, err := zapcore.ParseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		first, thereafter, ok := strings.Cut(rule, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid sampling rule %q, expected level=first:thereafter", item)
+		}
+
+		var r SamplingRule
+		if r.First, err = strconv.Atoi(first); err != nil {
+			return nil, fmt.Errorf("invalid sampling rule %q: %w", item, err)
+		}
+		if r.Thereafter, err = strconv.Atoi(thereafter); err != nil {
+			return nil, fmt.Errorf("invalid sampling rule %q: %w", item, err)
+		}
+		rules[level] = r
+	}
+
+	return rules, nil
+}
+
+// levelSampler samples every level with its own rule, levels without a rule are not sampled.
+type levelSampler struct {
+	zapcore.Core
+
+	samplers map[zapcore.Level]zapcore.Core
+}
+
+func newLevelSampler(core zapcore.Core, tick time.Duration, rules map[zapcore.Level]SamplingRule) zapcore.Core {
+	if len(rules) == 0 {
+		return core
+	}
+
+	samplers := make(map[zapcore.Level]zapcore.Core, len(rules))
+	for level, rule := range rules {
+		samplers[level] = zapcore.NewSamplerWithOptions(core, tick, rule.First, rule.Thereafter)
+	}
+
+	return &levelSampler{Core: core, samplers: samplers}
+}
+
+func (s *levelSampler) With(fields []zapcore.Field) zapcore.Core {
+	samplers := make(map[zapcore.Level]zapcore.Core, len(s.samplers))
+	for level, sampler := range s.samplers {
+		samplers[level] = sampler.With(fields)
+	}
+
+	return &levelSampler{Core: s.Core.With(fields), samplers: samplers}
+}
+
+func (s *levelSampler) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if sampler, ok := s.samplers[ent.Level]; ok {
+		return sampler.Check(ent, ce)
+	}
+
+	return s.Core.Check(ent, ce)
+}
+
+// parseRateLimit parses limits like "100/1s", an empty string disables rate limiting.
+func parseRateLimit(s string) (int, time.Duration, error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+
+	count, interval, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid rate limit %q, expected count/interval", s)
+	}
+
+	limit, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid rate limit %q: %w", s, err)
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid rate limit %q: %w", s, err)
+	}
+
+	return limit, d, nil
+}
+
+// maxRateLimitedMessages bounds the memory of the rate limiter, messages beyond it are not limited.
+const maxRateLimitedMessages = 1000
+
+type rateWindow struct {
+	start   time.Time
+	count   int
+	dropped int
+}
+
+// rateLimiter allows limit entries per message and interval and counts the dropped entries.
+type rateLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	interval time.Duration
+	windows  map[string]*rateWindow
+}
+
+// allow reports whether the entry may be logged and how many entries with the same message
+// were dropped in the previous window, which has to be reported.
+func (l *rateLimiter) allow(msg string, now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[msg]
+	if !ok {
+		if len(l.windows) >= maxRateLimitedMessages {
+			l.prune(now)
+		}
+		if len(l.windows) >= maxRateLimitedMessages {
+			return true, 0
+		}
+		w = &rateWindow{start: now}
+		l.windows[msg] = w
+	}
+
+	dropped := 0
+	if now.Sub(w.start) >= l.interval {
+		dropped = w.dropped
+		*w = rateWindow{start: now}
+	}
+
+	if w.count >= l.limit {
+		w.dropped++
+		return false, dropped
+	}
+	w.count++
+
+	return true, dropped
+}
+
+// prune must be called with l.mu held, it forgets finished windows without dropped entries.
+func (l *rateLimiter) prune(now time.Time) {
+	for msg, w := range l.windows {
+		if now.Sub(w.start) >= l.interval && w.dropped == 0 {
+			delete(l.windows, msg)
+		}
+	}
+}
+
+// flush returns and resets the dropped counts of all messages.
+func (l *rateLimiter) flush() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dropped := make(map[string]int)
+	for msg, w := range l.windows {
+		if w.dropped > 0 {
+			dropped[msg] = w.dropped
+			w.dropped = 0
+		}
+	}
+
+	return dropped
+}
+
+// rateLimitCore drops entries beyond the limit of their message and logs how many were dropped
+// once the next window of the message starts or the logger is synced.
+type rateLimitCore struct {
+	zapcore.Core
+
+	limiter *rateLimiter
+}
+
+func newRateLimitCore(core zapcore.Core, limit int, interval time.Duration) zapcore.Core {
+	if limit <= 0 {
+		return core
+	}
+
+	return &rateLimitCore{
+		Core: core,
+		limiter: &rateLimiter{
+			limit:    limit,
+			interval: interval,
+			windows:  make(map[string]*rateWindow),
+		},
+	}
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), limiter: c.limiter}
+}
+
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	allowed, dropped := c.limiter.allow(ent.Message, ent.Time)
+	if dropped > 0 {
+		c.reportDropped(ent.Message, dropped)
+	}
+	if !allowed {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
+
+func (c *rateLimitCore) Sync() error {
+	for msg, dropped := range c.limiter.flush() {
+		c.reportDropped(msg, dropped)
+	}
+
+	return c.Core.Sync()
+}
+
+func (c *rateLimitCore) reportDropped(msg string, dropped int) {
+	ent := zapcore.Entry{
+		Level:   zapcore.WarnLevel,
+		Time:    time.Now(),
+		Message: "dropped log entries exceeding the rate limit",
+	}
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(
+			zap.String("dropped_message", msg),
+			zap.Int("dropped", dropped),
+			zap.String("interval", c.limiter.interval.String()),
+		)
+	}
+}
@@ -0,0 +1,138 @@
+// Package logsink registers additional zap sinks, so they can be used as output paths of a zap.Config:
+//
+//	rotate:///var/log/service.log?max_size_mb=100&max_age=168h&max_backups=5
+//	syslog:///?tag=service-1          local syslog daemon
+//	syslog://host:514?tag=service-1   remote syslog via UDP
+//	tcp://localhost:5170
+//	udp://localhost:5170
+package logsink
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+var register = sync.OnceValue(func() error {
+	for scheme, factory := range map[string]func(*url.URL) (zap.Sink, error){
+		"rotate": newRotatingFile,
+		"syslog": newSyslog,
+		"tcp":    newNetwork,
+		"udp":    newNetwork,
+	} {
+		if err := zap.RegisterSink(scheme, factory); err != nil {
+			return fmt.Errorf("failed to register %s sink: %w", scheme, err)
+		}
+	}
+
+	return nil
+})
+
+// Register makes the sinks available to zap, it can be called more than once.
+func Register() error {
+	return register()
+}
+
+// rotatingFile is a lumberjack.Logger, which doesn't need to be synced.
+type rotatingFile struct {
+	*lumberjack.Logger
+}
+
+func (f rotatingFile) Sync() error {
+	return nil
+}
+
+func newRotatingFile(u *url.URL) (zap.Sink, error) {
+	query := u.Query()
+	file := &lumberjack.Logger{
+		Filename: u.Path,
+		MaxSize:  100,
+	}
+
+	var err error
+	if size := query.Get("max_size_mb"); size != "" {
+		if file.MaxSize, err = strconv.Atoi(size); err != nil {
+			return nil, fmt.Errorf("invalid max_size_mb: %w", err)
+		}
+	}
+	if age := query.Get("max_age"); age != "" {
+		d, err := time.ParseDuration(age)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_age: %w", err)
+		}
+		// lumberjack only supports whole days, round up so files are never removed too early.
+		file.MaxAge = int((d + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+	if backups := query.Get("max_backups"); backups != "" {
+		if file.MaxBackups, err = strconv.Atoi(backups); err != nil {
+			return nil, fmt.Errorf("invalid max_backups: %w", err)
+		}
+	}
+	file.Compress = query.Get("compress") == "true"
+
+	return rotatingFile{file}, nil
+}
+
+// network writes every entry to a TCP or UDP endpoint, e.g. a local log shipper.
+// It reconnects on the next write after a failure and never blocks longer than the timeout.
+type network struct {
+	mu      sync.Mutex
+	network string
+	address string
+	conn    net.Conn
+}
+
+const networkTimeout = time.Second
+
+func newNetwork(u *url.URL) (zap.Sink, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s sink needs a host", u.Scheme)
+	}
+
+	return &network{network: u.Scheme, address: u.Host}, nil
+}
+
+func (n *network) Write(p []byte) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		conn, err := net.DialTimeout(n.network, n.address, networkTimeout)
+		if err != nil {
+			return 0, err
+		}
+		n.conn = conn
+	}
+
+	_ = n.conn.SetWriteDeadline(time.Now().Add(networkTimeout))
+	written, err := n.conn.Write(p)
+	if err != nil {
+		_ = n.conn.Close()
+		n.conn = nil
+	}
+
+	return written, err
+}
+
+func (n *network) Sync() error {
+	return nil
+}
+
+func (n *network) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+
+	return err
+}
@@ -0,0 +1,33 @@
+//go:build !windows && !plan9
+
+package logsink
+
+import (
+	"log/syslog"
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+type syslogSink struct {
+	*syslog.Writer
+}
+
+func (s syslogSink) Sync() error {
+	return nil
+}
+
+// newSyslog writes the JSON entries with the info priority, the level is part of the entry.
+func newSyslog(u *url.URL) (zap.Sink, error) {
+	network := ""
+	if u.Host != "" {
+		network = "udp"
+	}
+
+	w, err := syslog.Dial(network, u.Host, syslog.LOG_INFO|syslog.LOG_DAEMON, u.Query().Get("tag"))
+	if err != nil {
+		return nil, err
+	}
+
+	return syslogSink{w}, nil
+}
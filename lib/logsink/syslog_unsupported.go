@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package logsink
+
+import (
+	"errors"
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+func newSyslog(_ *url.URL) (zap.Sink, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,160 @@
+package lib
+
+import (
+	"context"
+	"net/http"
+	"observability-demo/lib/health"
+	"strconv"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const MetricsPath = "/metrics"
+
+// NewRegistry creates a registry with the Go runtime and process collectors.
+// Every service uses its own registry instead of the global one.
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return reg
+}
+
+// MetricsHandler serves the metrics of reg in the OpenMetrics format if the scraper supports it,
+// which is required to expose exemplars.
+func MetricsHandler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+		Registry:          reg,
+	})
+}
+
+// TracedRequest is an otelhttp filter which excludes metric scrapes and health probes from tracing.
+func TracedRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case MetricsPath, health.LivezPath, health.ReadyzPath, health.HealthzPath:
+		return false
+	default:
+		return true
+	}
+}
+
+// HTTPMetrics records rate, errors and duration of HTTP requests.
+type HTTPMetrics struct {
+	baggage *Baggage
+
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+// NewHTTPMetrics labels the metrics by route, method, code and the metric labels of baggage, which may be nil.
+func NewHTTPMetrics(reg prometheus.Registerer, baggage *Baggage) *HTTPMetrics {
+	sizeBuckets := prometheus.ExponentialBuckets(64, 4, 8)
+	labels := func(names ...string) []string {
+		return append(names, baggage.LabelNames()...)
+	}
+
+	m := &HTTPMetrics{
+		baggage: baggage,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_server_requests_total",
+			Help: "Total number of HTTP requests.",
+		}, labels("route", "method", "code")),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:                            "http_server_request_duration_seconds",
+			Help:                            "Duration of HTTP requests.",
+			Buckets:                         prometheus.DefBuckets,
+			NativeHistogramBucketFactor:     1.1,
+			NativeHistogramMaxBucketNumber:  100,
+			NativeHistogramMinResetDuration: time.Hour,
+		}, labels("route", "method", "code")),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_server_requests_in_flight",
+			Help: "Number of HTTP requests currently being served.",
+		}),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_request_size_bytes",
+			Help:    "Size of HTTP request bodies.",
+			Buckets: sizeBuckets,
+		}, labels("route", "method")),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_response_size_bytes",
+			Help:    "Size of HTTP response bodies.",
+			Buckets: sizeBuckets,
+		}, labels("route", "method", "code")),
+	}
+
+	reg.MustRegister(m.requests, m.duration, m.inFlight, m.requestSize, m.responseSize)
+
+	return m
+}
+
+// Middleware records the metrics for every request.
+// It has to be wrapped by the otelhttp handler to attach the trace ID as exemplar and to read the baggage.
+func (m *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		snoop := httpsnoop.CaptureMetrics(next, w, r)
+
+		// ServeMux sets the matched pattern on the request while routing.
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		code := strconv.Itoa(snoop.Code)
+		ctx := r.Context()
+		bag := m.baggage.LabelValues(ctx)
+
+		IncWithExemplar(ctx, m.requests.WithLabelValues(append([]string{route, r.Method, code}, bag...)...))
+		ObserveWithExemplar(ctx, m.duration.WithLabelValues(append([]string{route, r.Method, code}, bag...)...), snoop.Duration.Seconds())
+		if r.ContentLength >= 0 {
+			ObserveWithExemplar(ctx, m.requestSize.WithLabelValues(append([]string{route, r.Method}, bag...)...), float64(r.ContentLength))
+		}
+		ObserveWithExemplar(ctx, m.responseSize.WithLabelValues(append([]string{route, r.Method, code}, bag...)...), float64(snoop.Written))
+	})
+}
+
+// Exemplar returns the trace ID of the current span as exemplar labels, or nil if the span is not sampled.
+func Exemplar(ctx context.Context) prometheus.Labels {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsSampled() {
+		return nil
+	}
+
+	return prometheus.Labels{"trace_id": spanCtx.TraceID().String()}
+}
+
+// IncWithExemplar increments counter and attaches the trace ID of ctx as exemplar if its span is sampled.
+func IncWithExemplar(ctx context.Context, counter prometheus.Counter) {
+	if exemplar := Exemplar(ctx); exemplar != nil {
+		if adder, ok := counter.(prometheus.ExemplarAdder); ok {
+			adder.AddWithExemplar(1, exemplar)
+			return
+		}
+	}
+	counter.Inc()
+}
+
+// ObserveWithExemplar records value and attaches the trace ID of ctx as exemplar if its span is sampled.
+func ObserveWithExemplar(ctx context.Context, observer prometheus.Observer, value float64) {
+	if exemplar := Exemplar(ctx); exemplar != nil {
+		if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+			exemplarObserver.ObserveWithExemplar(value, exemplar)
+			return
+		}
+	}
+	observer.Observe(value)
+}
@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"observability-demo/lib/telemetrytest"
+	"testing"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPMetricsExemplars(t *testing.T) {
+	// The parent decides about the sampling, so the test can send a sampled and an unsampled trace.
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())))
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+	})
+
+	reg := NewRegistry()
+	metrics := NewHTTPMetrics(reg, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /get", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle(MetricsPath, MetricsHandler(reg))
+	srv := httptest.NewServer(otelhttp.NewHandler(metrics.Middleware(mux), "/",
+		otelhttp.WithTracerProvider(tp),
+		otelhttp.WithPropagators(propagation.TraceContext{}),
+	))
+	t.Cleanup(srv.Close)
+
+	sampled := send(t, srv.URL+"/get", "4bf92f3577b34da6a3ce929d0e0e4736", true)
+	unsampled := send(t, srv.URL+"/get", "0af7651916cd43dd8448eb211c80319c", false)
+
+	exposition := telemetrytest.ScrapeOpenMetrics(t, srv.URL+MetricsPath)
+	for _, metric := range []string{"http_server_requests_total", "http_server_request_duration_seconds"} {
+		telemetrytest.AssertExemplar(t, exposition, metric, sampled)
+		telemetrytest.AssertNoExemplar(t, exposition, metric, unsampled)
+	}
+}
+
+// send makes a request as part of the trace, whose sampling was decided by the caller.
+func send(t *testing.T, url, traceID string, sampled bool) trace.TraceID {
+	t.Helper()
+
+	flags := "00"
+	if sampled {
+		flags = "01"
+	}
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-"+flags)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	id, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
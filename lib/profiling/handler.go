@@ -0,0 +1,106 @@
+package profiling
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const ProfilesPath = "/debug/profiles"
+
+// listedWindow is a Window with the path to download it.
+type listedWindow struct {
+	Window
+	URL string `json:"url"`
+}
+
+// Handle registers the endpoints of the stored profiles on mux:
+//
+//	GET /debug/profiles         lists the profiles, filtered by ?type=cpu|heap|goroutine and ?at=<RFC 3339 time>
+//	GET /debug/profiles/{name}  downloads a profile, e.g. with go tool pprof
+//
+// ?at returns the CPU profile which ran at the time and the heap and goroutine snapshots taken right before
+// and after it, use the start time of a slow span. The name of the CPU profile is also on the span as profiling.window.
+func (p *Profiler) Handle(mux *http.ServeMux) {
+	if p == nil {
+		return
+	}
+
+	mux.HandleFunc("GET "+ProfilesPath, p.serveList)
+	mux.HandleFunc("GET "+ProfilesPath+"/{name}", p.serveProfile)
+}
+
+func (p *Profiler) serveList(w http.ResponseWriter, r *http.Request) {
+	windows := p.Windows()
+
+	if at := r.URL.Query().Get("at"); at != "" {
+		t, err := time.Parse(time.RFC3339Nano, at)
+		if err != nil {
+			http.Error(w, "invalid at: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		windows = around(windows, t)
+	}
+
+	listed := []listedWindow{}
+	typ := r.URL.Query().Get("type")
+	for _, window := range windows {
+		if typ != "" && window.Type != typ {
+			continue
+		}
+		listed = append(listed, listedWindow{Window: window, URL: ProfilesPath + "/" + window.Name})
+	}
+
+	body, err := json.Marshal(listed)
+	if err != nil {
+		http.Error(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// serveProfile only serves the files of known windows, so the name can't escape the directory.
+func (p *Profiler) serveProfile(w http.ResponseWriter, r *http.Request) {
+	window, ok := p.Window(r.PathValue("name"))
+	if !ok {
+		http.Error(w, "profile not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", window.Name))
+	http.ServeFile(w, r, p.Path(window))
+}
+
+// around returns the CPU profiles which contain t and the last snapshot of each type before t and the first after t.
+// windows are ordered by their start.
+func around(windows []Window, t time.Time) []Window {
+	var result []Window
+	before := make(map[string]Window)
+	after := make(map[string]Window)
+	for _, window := range windows {
+		switch {
+		case window.Type == TypeCPU:
+			if window.Contains(t) {
+				result = append(result, window)
+			}
+		case window.Start.After(t):
+			if _, ok := after[window.Type]; !ok {
+				after[window.Type] = window
+			}
+		default:
+			before[window.Type] = window
+		}
+	}
+	for _, typ := range []string{TypeHeap, TypeGoroutine} {
+		for _, snapshots := range []map[string]Window{before, after} {
+			if window, ok := snapshots[typ]; ok {
+				result = append(result, window)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,45 @@
+package profiling
+
+import (
+	"context"
+	"runtime/pprof"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	LabelSpanID  = "span_id"
+	LabelTraceID = "trace_id"
+	LabelRoute   = "route"
+)
+
+// WindowKey is the name of the CPU profile which was running when the span started.
+const WindowKey = attribute.Key("profiling.window")
+
+// currentWindow is the name of the running CPU profile. The CPU profiler of the runtime is global,
+// so there is at most one per process.
+var currentWindow atomic.Pointer[string]
+
+// Label sets the pprof labels span_id, trace_id and route on the current goroutine, so the samples
+// of the CPU profiles can be attributed to the span of ctx, e.g. with go tool pprof -tagfocus span_id=<id>.
+// Goroutines started with the returned context inherit the labels.
+// If a CPU profile is running, the span gets its name as profiling.window.
+// Call the returned function to restore the labels when the handler returns.
+func Label(ctx context.Context, route string) (context.Context, func()) {
+	labels := []string{LabelRoute, route}
+	span := trace.SpanFromContext(ctx)
+	if sc := span.SpanContext(); sc.IsValid() {
+		labels = append(labels, LabelTraceID, sc.TraceID().String(), LabelSpanID, sc.SpanID().String())
+	}
+	if window := currentWindow.Load(); window != nil {
+		span.SetAttributes(WindowKey.String(*window))
+	}
+
+	parent := ctx
+	ctx = pprof.WithLabels(ctx, pprof.Labels(labels...))
+	pprof.SetGoroutineLabels(ctx)
+
+	return ctx, func() { pprof.SetGoroutineLabels(parent) }
+}
@@ -0,0 +1,349 @@
+// Package profiling captures CPU, heap and goroutine profiles periodically and keeps them in a local directory,
+// so the profile of the time a slow span ran in can be fetched after the fact.
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"observability-demo/lib"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	TypeCPU       = "cpu"
+	TypeHeap      = "heap"
+	TypeGoroutine = "goroutine"
+)
+
+// fileSuffix is the suffix of the profiles, pprof writes them as gzipped protocol buffers.
+const fileSuffix = ".pb.gz"
+
+type Config struct {
+	// Dir stores the profiles, an empty directory disables the profiling.
+	Dir string
+	// Interval is the time between the start of two captures.
+	Interval time.Duration
+	// CPUDuration is the length of the CPU profile of each capture.
+	CPUDuration time.Duration
+	// Retention is how long the profiles are kept.
+	Retention time.Duration
+}
+
+// LoadConfig reads the configuration from the environment:
+// PROFILING_DIR, PROFILING_INTERVAL, PROFILING_CPU_DURATION and PROFILING_RETENTION.
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		Dir: lib.GetEnv("PROFILING_DIR", ""),
+	}
+
+	var err error
+	if cfg.Interval, err = time.ParseDuration(lib.GetEnv("PROFILING_INTERVAL", "1m")); err != nil || cfg.Interval <= 0 {
+		return Config{}, fmt.Errorf("invalid PROFILING_INTERVAL %q", lib.GetEnv("PROFILING_INTERVAL", ""))
+	}
+	if cfg.CPUDuration, err = time.ParseDuration(lib.GetEnv("PROFILING_CPU_DURATION", "10s")); err != nil || cfg.CPUDuration <= 0 {
+		return Config{}, fmt.Errorf("invalid PROFILING_CPU_DURATION %q", lib.GetEnv("PROFILING_CPU_DURATION", ""))
+	}
+	if cfg.Retention, err = time.ParseDuration(lib.GetEnv("PROFILING_RETENTION", "1h")); err != nil || cfg.Retention <= 0 {
+		return Config{}, fmt.Errorf("invalid PROFILING_RETENTION %q", lib.GetEnv("PROFILING_RETENTION", ""))
+	}
+	if cfg.CPUDuration > cfg.Interval {
+		return Config{}, fmt.Errorf("PROFILING_CPU_DURATION %s is longer than PROFILING_INTERVAL %s", cfg.CPUDuration, cfg.Interval)
+	}
+
+	return cfg, nil
+}
+
+// Window is a stored profile. CPU profiles cover the time from Start to End,
+// heap and goroutine profiles are snapshots which start and end at the same time.
+type Window struct {
+	Name  string    `json:"name"`
+	Type  string    `json:"type"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Size  int64     `json:"size"`
+}
+
+// Contains tells whether t is part of the window.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && !t.After(w.End)
+}
+
+// Profiler captures the profiles of the process. A nil Profiler does nothing.
+type Profiler struct {
+	cfg     Config
+	service string
+	now     func() time.Time
+
+	mu      sync.Mutex
+	windows []Window
+
+	captures *prometheus.CounterVec
+	log      *zap.SugaredLogger
+}
+
+// New returns nil if cfg has no directory. The profiles which are still in the directory are kept
+// until their retention ends, profiles of other services in the same directory are ignored.
+func New(service string, cfg Config, reg prometheus.Registerer, log *zap.SugaredLogger) (*Profiler, error) {
+	if cfg.Dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create profiling directory: %w", err)
+	}
+
+	p := &Profiler{
+		cfg:     cfg,
+		service: service,
+		now:     time.Now,
+		captures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "profiling_captures_total",
+			Help: "Captured profiles by type and result.",
+		}, []string{"type", "result"}),
+		log: log,
+	}
+	reg.MustRegister(p.captures, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "profiling_stored_bytes",
+		Help: "Size of the profiles in the profiling directory.",
+	}, p.storedBytes))
+
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Run captures the profiles every interval until ctx is cancelled and removes the profiles after their retention.
+func (p *Profiler) Run(ctx context.Context) {
+	if p == nil {
+		return
+	}
+
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.capture(ctx)
+		p.prune()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Windows returns the stored profiles ordered by their start.
+func (p *Profiler) Windows() []Window {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.windows)
+}
+
+// Window returns the stored profile with the name.
+func (p *Profiler) Window(name string) (Window, bool) {
+	for _, w := range p.Windows() {
+		if w.Name == name {
+			return w, true
+		}
+	}
+
+	return Window{}, false
+}
+
+// Path returns the file of a window.
+func (p *Profiler) Path(w Window) string {
+	return filepath.Join(p.cfg.Dir, w.Name)
+}
+
+// capture takes the CPU profile and then the heap and goroutine snapshots.
+func (p *Profiler) capture(ctx context.Context) {
+	if err := p.captureCPU(ctx); err != nil {
+		p.failed(TypeCPU, err)
+	}
+	for _, typ := range []string{TypeHeap, TypeGoroutine} {
+		if err := p.captureSnapshot(typ); err != nil {
+			p.failed(typ, err)
+		}
+	}
+}
+
+func (p *Profiler) captureCPU(ctx context.Context) error {
+	file, err := os.CreateTemp(p.cfg.Dir, ".cpu-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	start := p.now()
+	// Fails if another CPU profile is running, e.g. one requested from /debug/pprof/profile.
+	if err := pprof.StartCPUProfile(file); err != nil {
+		return err
+	}
+	window := p.windowName(TypeCPU, start, start.Add(p.cfg.CPUDuration))
+	currentWindow.Store(&window)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(p.cfg.CPUDuration):
+	}
+	currentWindow.Store(nil)
+	pprof.StopCPUProfile()
+	end := p.now()
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+	// The name is chosen before the profile starts, so the spans of the window carry it.
+	return p.store(file.Name(), TypeCPU, start, end, window)
+}
+
+func (p *Profiler) captureSnapshot(typ string) error {
+	file, err := os.CreateTemp(p.cfg.Dir, "."+typ+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	now := p.now()
+	if err := pprof.Lookup(typ).WriteTo(file, 0); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return p.store(file.Name(), typ, now, now, p.windowName(typ, now, now))
+}
+
+// store moves a captured profile to its final name and adds it to the windows.
+func (p *Profiler) store(tmp, typ string, start, end time.Time, name string) error {
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(p.cfg.Dir, name)); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.windows = append(p.windows, Window{Name: name, Type: typ, Start: start, End: end, Size: info.Size()})
+	p.mu.Unlock()
+	p.captures.WithLabelValues(typ, "ok").Inc()
+
+	return nil
+}
+
+func (p *Profiler) failed(typ string, err error) {
+	p.captures.WithLabelValues(typ, "failed").Inc()
+	p.log.Warnw("failed to capture profile", "type", typ, "error", err)
+}
+
+// prune removes the profiles which ended before the retention.
+func (p *Profiler) prune() {
+	deadline := p.now().Add(-p.cfg.Retention)
+
+	p.mu.Lock()
+	var expired []Window
+	p.windows = slices.DeleteFunc(p.windows, func(w Window) bool {
+		if w.End.Before(deadline) {
+			expired = append(expired, w)
+			return true
+		}
+		return false
+	})
+	p.mu.Unlock()
+
+	for _, w := range expired {
+		if err := os.Remove(p.Path(w)); err != nil && !os.IsNotExist(err) {
+			p.log.Warnw("failed to remove profile", "name", w.Name, "error", err)
+		}
+	}
+}
+
+func (p *Profiler) storedBytes() float64 {
+	var size int64
+	for _, w := range p.Windows() {
+		size += w.Size
+	}
+
+	return float64(size)
+}
+
+// windowName is <service>-<type>-<start>-<end>.pb.gz with the times in Unix milliseconds.
+func (p *Profiler) windowName(typ string, start, end time.Time) string {
+	return fmt.Sprintf("%s-%s-%d-%d%s", p.service, typ, start.UnixMilli(), end.UnixMilli(), fileSuffix)
+}
+
+// parseWindowName is the reverse of windowName, it fails for files of other services.
+func (p *Profiler) parseWindowName(name string) (Window, bool) {
+	rest, ok := strings.CutPrefix(name, p.service+"-")
+	if !ok {
+		return Window{}, false
+	}
+	rest, ok = strings.CutSuffix(rest, fileSuffix)
+	if !ok {
+		return Window{}, false
+	}
+	parts := strings.Split(rest, "-")
+	if len(parts) != 3 {
+		return Window{}, false
+	}
+	start, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return Window{}, false
+	}
+	end, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return Window{}, false
+	}
+
+	switch parts[0] {
+	case TypeCPU, TypeHeap, TypeGoroutine:
+		return Window{Name: name, Type: parts[0], Start: time.UnixMilli(start), End: time.UnixMilli(end)}, true
+	default:
+		return Window{}, false
+	}
+}
+
+// load adds the profiles of a previous run of the service.
+func (p *Profiler) load() error {
+	entries, err := os.ReadDir(p.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read profiling directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		w, ok := p.parseWindowName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		w.Size = info.Size()
+		p.windows = append(p.windows, w)
+	}
+	slices.SortFunc(p.windows, func(a, b Window) int { return a.Start.Compare(b.Start) })
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"observability-demo/lib"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// LimitKey is the concurrency limit when a call was rejected.
+	LimitKey = attribute.Key("concurrency.limit")
+	// InflightKey is the number of calls in flight when a call was rejected.
+	InflightKey = attribute.Key("concurrency.inflight")
+)
+
+// backoff is the factor by which the limit is decreased after a slow or failed call.
+const backoff = 0.9
+
+// ErrLimited is returned for calls which are rejected because the concurrency limit is reached.
+var ErrLimited = errors.New("concurrency limit reached")
+
+type ConcurrencyConfig struct {
+	// MaxLimit caps the number of concurrent calls, 0 disables the limiter.
+	MaxLimit int
+	// MinLimit is the lowest the limit can drop to.
+	MinLimit int
+	// LatencyTarget is the latency of a healthy dependency, slower calls decrease the limit.
+	LatencyTarget time.Duration
+}
+
+// LoadConcurrencyConfig reads the limit of the calls to service-2 from the environment:
+// STORE_CONCURRENCY_LIMIT is the maximum limit, STORE_CONCURRENCY_MIN the minimum
+// and STORE_LATENCY_TARGET the latency above which the limit is decreased.
+func LoadConcurrencyConfig() (ConcurrencyConfig, error) {
+	var cfg ConcurrencyConfig
+	var err error
+
+	if cfg.MaxLimit, err = strconv.Atoi(lib.GetEnv("STORE_CONCURRENCY_LIMIT", "0")); err != nil || cfg.MaxLimit < 0 {
+		return ConcurrencyConfig{}, fmt.Errorf("invalid STORE_CONCURRENCY_LIMIT %q", lib.GetEnv("STORE_CONCURRENCY_LIMIT", ""))
+	}
+	if cfg.MinLimit, err = strconv.Atoi(lib.GetEnv("STORE_CONCURRENCY_MIN", "1")); err != nil || cfg.MinLimit < 1 {
+		return ConcurrencyConfig{}, fmt.Errorf("invalid STORE_CONCURRENCY_MIN %q", lib.GetEnv("STORE_CONCURRENCY_MIN", ""))
+	}
+	if cfg.LatencyTarget, err = time.ParseDuration(lib.GetEnv("STORE_LATENCY_TARGET", "100ms")); err != nil || cfg.LatencyTarget <= 0 {
+		return ConcurrencyConfig{}, fmt.Errorf("invalid STORE_LATENCY_TARGET %q", lib.GetEnv("STORE_LATENCY_TARGET", ""))
+	}
+	if cfg.MaxLimit > 0 && cfg.MinLimit > cfg.MaxLimit {
+		return ConcurrencyConfig{}, errors.New("STORE_CONCURRENCY_MIN is greater than STORE_CONCURRENCY_LIMIT")
+	}
+
+	return cfg, nil
+}
+
+// ConcurrencyLimiter limits the concurrent calls to a dependency with AIMD: every fast and successful call
+// increases the limit by 1/limit, so by about one per round of calls, every slow or failed call decreases it by 10%.
+// Calls above the limit are rejected right away, which sheds load before the dependency is overloaded.
+type ConcurrencyLimiter struct {
+	cfg ConcurrencyConfig
+	now func() time.Time
+
+	mu           sync.Mutex
+	limit        float64
+	inflight     int
+	lastDecrease time.Time
+
+	limitGauge    prometheus.Gauge
+	inflightGauge prometheus.Gauge
+	rejected      prometheus.Counter
+}
+
+// NewConcurrencyLimiter returns nil if the maximum limit is 0. The metrics are registered on reg
+// with the name of the dependency as limiter label.
+func NewConcurrencyLimiter(name string, cfg ConcurrencyConfig, reg prometheus.Registerer) *ConcurrencyLimiter {
+	if cfg.MaxLimit == 0 {
+		return nil
+	}
+
+	labels := prometheus.Labels{"limiter": name}
+	c := &ConcurrencyLimiter{
+		cfg:   cfg,
+		now:   time.Now,
+		limit: float64(max(cfg.MinLimit, cfg.MaxLimit/2)),
+		limitGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "concurrency_limit",
+			Help:        "Current adaptive limit of concurrent calls.",
+			ConstLabels: labels,
+		}),
+		inflightGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "concurrency_inflight",
+			Help:        "Calls in flight.",
+			ConstLabels: labels,
+		}),
+		rejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "concurrency_rejected_total",
+			Help:        "Calls which were rejected because the concurrency limit was reached.",
+			ConstLabels: labels,
+		}),
+	}
+	c.limitGauge.Set(c.limit)
+	reg.MustRegister(c.limitGauge, c.inflightGauge, c.rejected)
+
+	return c
+}
+
+// Acquire reserves a slot for a call or returns ErrLimited. The returned release function has to be called
+// once the call is done, with overloaded set if the call failed in a way which hints at an overloaded dependency.
+// A nil ConcurrencyLimiter allows every call.
+func (c *ConcurrencyLimiter) Acquire(ctx context.Context) (release func(overloaded bool), err error) {
+	if c == nil {
+		return func(bool) {}, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inflight >= int(c.limit) {
+		lib.IncWithExemplar(ctx, c.rejected)
+		trace.SpanFromContext(ctx).AddEvent("concurrency limited", trace.WithAttributes(
+			LimitKey.Int(int(c.limit)),
+			InflightKey.Int(c.inflight),
+		))
+		return nil, fmt.Errorf("%w: %d calls in flight", ErrLimited, c.inflight)
+	}
+
+	c.inflight++
+	c.inflightGauge.Set(float64(c.inflight))
+	start := c.now()
+
+	var once sync.Once
+	return func(overloaded bool) {
+		once.Do(func() {
+			c.release(c.now().Sub(start), overloaded)
+		})
+	}, nil
+}
+
+func (c *ConcurrencyLimiter) release(latency time.Duration, overloaded bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inflight--
+	c.inflightGauge.Set(float64(c.inflight))
+
+	now := c.now()
+	switch {
+	case overloaded || latency > c.cfg.LatencyTarget:
+		// The calls which were already in flight when the dependency slowed down would decrease the limit
+		// all at once, so it's decreased at most once per latency target.
+		if now.Sub(c.lastDecrease) < c.cfg.LatencyTarget {
+			return
+		}
+		c.lastDecrease = now
+		c.limit = max(float64(c.cfg.MinLimit), c.limit*backoff)
+	default:
+		c.limit = min(float64(c.cfg.MaxLimit), c.limit+1/c.limit)
+	}
+	c.limitGauge.Set(c.limit)
+}
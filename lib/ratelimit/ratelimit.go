@@ -0,0 +1,273 @@
+// Package ratelimit protects a service from noisy clients with a token bucket per client
+// and protects its dependencies with an adaptive concurrency limit.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"observability-demo/lib"
+	"observability-demo/lib/auth"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// KeyByAPIKey limits every authenticated caller on its own, unauthenticated requests are limited by IP.
+	KeyByAPIKey = "api-key"
+	// KeyByTenant limits every tenant of Config.Tenants on its own, requests of other tenants are limited by IP.
+	KeyByTenant = "tenant"
+	KeyByIP     = "ip"
+	// KeyByUnauthenticated limits the requests which fail the authentication by IP, see Unauthenticated.
+	KeyByUnauthenticated = "unauthenticated"
+)
+
+const (
+	// KeyByKey tells how the client of a throttled request was identified.
+	KeyByKey = attribute.Key("ratelimit.key_by")
+	// RetryAfterKey is the time after which the client has a token again.
+	RetryAfterKey = attribute.Key("ratelimit.retry_after_ms")
+)
+
+// sweepInterval is how often the buckets of idle clients are removed.
+const sweepInterval = time.Minute
+
+type Config struct {
+	// Rate is the number of requests per second of a client, 0 disables the limit.
+	Rate float64
+	// Burst is the number of requests a client can send at once, it defaults to the rate.
+	Burst int
+	// KeyBy identifies the clients, see KeyByAPIKey, KeyByTenant and KeyByIP.
+	KeyBy string
+	// Tenants get a bucket of their own with KeyByTenant. The tenant is chosen by the client,
+	// so any other tenant is limited by IP instead of getting a new bucket for every invented name.
+	Tenants []string
+}
+
+// LoadConfig reads the configuration from the environment:
+// RATE_LIMIT is the rate per client, RATE_LIMIT_BURST the burst and RATE_LIMIT_KEY identifies the clients.
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		KeyBy: lib.GetEnv("RATE_LIMIT_KEY", KeyByAPIKey),
+	}
+
+	var err error
+	if cfg.Rate, err = strconv.ParseFloat(lib.GetEnv("RATE_LIMIT", "0"), 64); err != nil || cfg.Rate < 0 {
+		return Config{}, fmt.Errorf("invalid RATE_LIMIT %q", lib.GetEnv("RATE_LIMIT", ""))
+	}
+	if cfg.Burst, err = strconv.Atoi(lib.GetEnv("RATE_LIMIT_BURST", "0")); err != nil || cfg.Burst < 0 {
+		return Config{}, fmt.Errorf("invalid RATE_LIMIT_BURST %q", lib.GetEnv("RATE_LIMIT_BURST", ""))
+	}
+	if cfg.Burst == 0 {
+		cfg.Burst = max(1, int(math.Ceil(cfg.Rate)))
+	}
+
+	switch cfg.KeyBy {
+	case KeyByAPIKey, KeyByTenant, KeyByIP:
+	default:
+		return Config{}, fmt.Errorf("invalid RATE_LIMIT_KEY %q, use api-key, tenant or ip", cfg.KeyBy)
+	}
+
+	return cfg, nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter limits the requests of every client with a token bucket. A nil Limiter lets every request through.
+type Limiter struct {
+	cfg Config
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	throttled *prometheus.CounterVec
+	clients   prometheus.Gauge
+	log       *zap.SugaredLogger
+}
+
+// NewLimiter returns nil if the rate is 0. The metrics are registered on reg.
+func NewLimiter(cfg Config, reg prometheus.Registerer, log *zap.SugaredLogger) *Limiter {
+	if cfg.Rate == 0 {
+		return nil
+	}
+
+	l := &Limiter{
+		cfg:     cfg,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ratelimit_throttled_requests_total",
+			Help: "Requests which were rejected because their client exceeded the rate limit.",
+		}, []string{"key_by"}),
+		clients: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ratelimit_clients",
+			Help: "Clients with a token bucket, idle clients are removed every minute.",
+		}),
+		log: log,
+	}
+	reg.MustRegister(l.throttled, l.clients)
+
+	return l
+}
+
+// Allow takes a token of the client. If it has none left, it returns false and the time until it has one again.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	return l.take(client, true)
+}
+
+// take refills the bucket of the client and takes a token if consume is true.
+func (l *Limiter) take(client string, consume bool) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.cfg.Burst), last: now}
+		l.buckets[client] = b
+		l.clients.Set(float64(len(l.buckets)))
+	}
+	b.tokens = min(float64(l.cfg.Burst), b.tokens+now.Sub(b.last).Seconds()*l.cfg.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		if consume {
+			b.tokens--
+		}
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.cfg.Rate * float64(time.Second))
+}
+
+// sweep removes the buckets which are full again, a new bucket would be the same.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	refill := time.Duration(float64(l.cfg.Burst) / l.cfg.Rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, client)
+		}
+	}
+	l.clients.Set(float64(len(l.buckets)))
+}
+
+// Middleware rejects the requests of clients which exceeded the limit with 429 and Retry-After.
+// It has to be wrapped by the authentication middleware to limit by API key.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, keyBy := l.client(r)
+		if ok, retryAfter := l.Allow(client); !ok {
+			l.reject(w, r, keyBy, retryAfter)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Unauthenticated rejects the requests of an IP whose requests failed the authentication too often with 429.
+// Only the requests answered with 401 take a token, so authenticated requests don't use up the budget of their IP.
+// It has to wrap the authentication middleware, the requests it rejects never reach Middleware.
+func (l *Limiter) Unauthenticated(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := KeyByUnauthenticated + ":" + remoteIP(r)
+		if ok, retryAfter := l.take(client, false); !ok {
+			l.reject(w, r, KeyByUnauthenticated, retryAfter)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status == http.StatusUnauthorized {
+			l.Allow(client)
+		}
+	})
+}
+
+// reject answers a throttled request.
+func (l *Limiter) reject(w http.ResponseWriter, r *http.Request, keyBy string, retryAfter time.Duration) {
+	span := trace.SpanFromContext(r.Context())
+	span.AddEvent("rate limited", trace.WithAttributes(
+		KeyByKey.String(keyBy),
+		RetryAfterKey.Int64(retryAfter.Milliseconds()),
+	))
+	lib.IncWithExemplar(r.Context(), l.throttled.WithLabelValues(keyBy))
+	lib.ContextLogger(r.Context(), l.log).Debugw("rate limited request", "key_by", keyBy, "retry_after", retryAfter.String())
+
+	SetRetryAfter(w, retryAfter)
+	lib.HTTPError(w, span, http.StatusTooManyRequests, fmt.Errorf("rate limit of %g requests per second exceeded", l.cfg.Rate))
+}
+
+// client identifies the client of a request and returns how it was identified.
+func (l *Limiter) client(r *http.Request) (string, string) {
+	switch l.cfg.KeyBy {
+	case KeyByAPIKey:
+		if p, ok := auth.PrincipalFrom(r.Context()); ok {
+			return KeyByAPIKey + ":" + p.Subject, KeyByAPIKey
+		}
+	case KeyByTenant:
+		if tenant, err := lib.ResolveTenant(r, l.cfg.Tenants); err == nil && slices.Contains(l.cfg.Tenants, tenant) {
+			return KeyByTenant + ":" + tenant, KeyByTenant
+		}
+	}
+
+	return KeyByIP + ":" + remoteIP(r), KeyByIP
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// SetRetryAfter sets the Retry-After header in whole seconds, rounded up so the client doesn't retry too early.
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(d.Seconds())))))
+}
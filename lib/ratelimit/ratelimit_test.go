@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"observability-demo/lib"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+func TestKeyByTenantFallsBackToIP(t *testing.T) {
+	limiter := NewLimiter(Config{Rate: 1, Burst: 1, KeyBy: KeyByTenant, Tenants: []string{"acme"}},
+		prometheus.NewRegistry(), zap.NewNop().Sugar())
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(tenant, ip string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set(lib.TenantHeader, tenant)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A listed tenant has its own bucket, independent of the IP.
+	if code := serve("acme", "10.0.0.1"); code != http.StatusOK {
+		t.Fatalf("expected 200 for the first request of acme, got %d", code)
+	}
+	if code := serve("acme", "10.0.0.2"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for the second request of acme, got %d", code)
+	}
+
+	// Rotating unlisted tenants doesn't get a fresh bucket, they share the bucket of the IP.
+	if code := serve("invented-1", "10.0.0.3"); code != http.StatusOK {
+		t.Fatalf("expected 200 for the first request of the IP, got %d", code)
+	}
+	if code := serve("invented-2", "10.0.0.3"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for the second request of the IP, got %d", code)
+	}
+}
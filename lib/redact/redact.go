@@ -0,0 +1,145 @@
+// Package redact removes secrets from span attributes and log fields before they leave the process
+// and limits the number and length of attributes.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Redacted replaces redacted values.
+const Redacted = "[REDACTED]"
+
+type Config struct {
+	// Keys are attribute and log field keys whose values are replaced completely, compared case-insensitively.
+	Keys []string
+	// QueryParams are URL query parameters whose values are replaced in all strings.
+	QueryParams []string
+	// Patterns are replaced in all strings.
+	Patterns []*regexp.Regexp
+
+	// MaxAttributes limits the attributes per span and event, zero means unlimited.
+	MaxAttributes int
+	// MaxEvents limits the events per span, zero means unlimited.
+	MaxEvents int
+	// MaxValueLength truncates string values to the number of bytes, zero means unlimited.
+	MaxValueLength int
+}
+
+// DefaultConfig redacts the values of the key-value store and common credentials.
+func DefaultConfig() Config {
+	return Config{
+		Keys:        []string{"value", "password", "secret", "token", "api_key", "authorization", "http.request.header.authorization"},
+		QueryParams: []string{"value", "password", "token", "api_key"},
+		Patterns: []*regexp.Regexp{
+			// Bearer tokens in authorization headers.
+			regexp.MustCompile(`(?i)bearer\s+[a-z0-9._~+/-]+=*`),
+			// JSON web tokens.
+			regexp.MustCompile(`eyJ[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]*`),
+		},
+		MaxAttributes:  128,
+		MaxEvents:      128,
+		MaxValueLength: 1024,
+	}
+}
+
+// Redactor applies a Config, it is safe for concurrent use.
+type Redactor struct {
+	cfg   Config
+	keys  map[string]struct{}
+	query *regexp.Regexp
+}
+
+func New(cfg Config) *Redactor {
+	r := &Redactor{
+		cfg:  cfg,
+		keys: make(map[string]struct{}, len(cfg.Keys)),
+	}
+	for _, key := range cfg.Keys {
+		r.keys[strings.ToLower(key)] = struct{}{}
+	}
+
+	if len(cfg.QueryParams) > 0 {
+		params := make([]string, 0, len(cfg.QueryParams))
+		for _, param := range cfg.QueryParams {
+			params = append(params, regexp.QuoteMeta(param))
+		}
+		// The value of a parameter ends at the next parameter, fragment, whitespace or quote.
+		r.query = regexp.MustCompile(fmt.Sprintf(`(^|[?&])(%s)=[^&#\s"]*`, strings.Join(params, "|")))
+	}
+
+	return r
+}
+
+// IsRedactedKey reports whether the values of key are replaced completely.
+func (r *Redactor) IsRedactedKey(key string) bool {
+	_, ok := r.keys[strings.ToLower(key)]
+	return ok
+}
+
+// String replaces query parameters and patterns in s and truncates it.
+func (r *Redactor) String(s string) string {
+	if r.query != nil {
+		s = r.query.ReplaceAllString(s, "${1}${2}="+Redacted)
+	}
+	for _, pattern := range r.cfg.Patterns {
+		s = pattern.ReplaceAllString(s, Redacted)
+	}
+
+	return r.truncate(s)
+}
+
+func (r *Redactor) truncate(s string) string {
+	if r.cfg.MaxValueLength <= 0 || len(s) <= r.cfg.MaxValueLength {
+		return s
+	}
+
+	// Don't cut a multi-byte character in half.
+	end := r.cfg.MaxValueLength
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+
+	return s[:end]
+}
+
+// Attribute redacts a single attribute.
+func (r *Redactor) Attribute(kv attribute.KeyValue) attribute.KeyValue {
+	if r.IsRedactedKey(string(kv.Key)) {
+		return kv.Key.String(Redacted)
+	}
+
+	switch kv.Value.Type() {
+	case attribute.STRING:
+		return kv.Key.String(r.String(kv.Value.AsString()))
+	case attribute.STRINGSLICE:
+		values := kv.Value.AsStringSlice()
+		redacted := make([]string, len(values))
+		for i, v := range values {
+			redacted[i] = r.String(v)
+		}
+		return kv.Key.StringSlice(redacted)
+	default:
+		return kv
+	}
+}
+
+// Attributes redacts attrs and drops the attributes beyond the limit, it returns the number of dropped attributes.
+func (r *Redactor) Attributes(attrs []attribute.KeyValue) ([]attribute.KeyValue, int) {
+	dropped := 0
+	if r.cfg.MaxAttributes > 0 && len(attrs) > r.cfg.MaxAttributes {
+		dropped = len(attrs) - r.cfg.MaxAttributes
+		attrs = attrs[:r.cfg.MaxAttributes]
+	}
+
+	redacted := make([]attribute.KeyValue, len(attrs))
+	for i, kv := range attrs {
+		redacted[i] = r.Attribute(kv)
+	}
+
+	return redacted, dropped
+}
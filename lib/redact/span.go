@@ -0,0 +1,97 @@
+package redact
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// SpanProcessor redacts ended spans before passing them to the next processor, e.g. the batch processor.
+// Attributes can still change until a span ends, so redacting in OnStart would miss most of them.
+type SpanProcessor struct {
+	next     sdktrace.SpanProcessor
+	redactor *Redactor
+}
+
+func NewSpanProcessor(next sdktrace.SpanProcessor, redactor *Redactor) *SpanProcessor {
+	return &SpanProcessor{next: next, redactor: redactor}
+}
+
+func (p *SpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p *SpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.next.OnEnd(p.redact(s))
+}
+
+func (p *SpanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p *SpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+func (p *SpanProcessor) redact(s sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	attrs, droppedAttrs := p.redactor.Attributes(s.Attributes())
+
+	events := s.Events()
+	droppedEvents := 0
+	if max := p.redactor.cfg.MaxEvents; max > 0 && len(events) > max {
+		droppedEvents = len(events) - max
+		events = events[:max]
+	}
+
+	redactedEvents := make([]sdktrace.Event, len(events))
+	for i, event := range events {
+		eventAttrs, dropped := p.redactor.Attributes(event.Attributes)
+		event.Attributes = eventAttrs
+		event.DroppedAttributeCount += dropped
+		redactedEvents[i] = event
+	}
+
+	status := s.Status()
+	status.Description = p.redactor.String(status.Description)
+
+	return &redactedSpan{
+		ReadOnlySpan:  s,
+		attrs:         attrs,
+		droppedAttrs:  droppedAttrs,
+		events:        redactedEvents,
+		droppedEvents: droppedEvents,
+		status:        status,
+	}
+}
+
+// redactedSpan overrides the redacted parts of the original span.
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+
+	attrs         []attribute.KeyValue
+	droppedAttrs  int
+	events        []sdktrace.Event
+	droppedEvents int
+	status        sdktrace.Status
+}
+
+func (s *redactedSpan) Attributes() []attribute.KeyValue {
+	return s.attrs
+}
+
+func (s *redactedSpan) DroppedAttributes() int {
+	return s.ReadOnlySpan.DroppedAttributes() + s.droppedAttrs
+}
+
+func (s *redactedSpan) Events() []sdktrace.Event {
+	return s.events
+}
+
+func (s *redactedSpan) DroppedEvents() int {
+	return s.ReadOnlySpan.DroppedEvents() + s.droppedEvents
+}
+
+func (s *redactedSpan) Status() sdktrace.Status {
+	return s.status
+}
@@ -0,0 +1,58 @@
+package redact
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// core redacts the message and fields of log entries before passing them to the wrapped core.
+type core struct {
+	zapcore.Core
+
+	redactor *Redactor
+}
+
+// NewCore wraps c so every entry written to it is redacted.
+func NewCore(c zapcore.Core, redactor *Redactor) zapcore.Core {
+	return &core{Core: c, redactor: redactor}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(c.fields(fields)), redactor: c.redactor}
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.redactor.String(ent.Message)
+
+	return c.Core.Write(ent, c.fields(fields))
+}
+
+func (c *core) fields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch {
+		case c.redactor.IsRedactedKey(field.Key):
+			redacted[i] = zap.String(field.Key, Redacted)
+		case field.Type == zapcore.StringType:
+			redacted[i] = zap.String(field.Key, c.redactor.String(field.String))
+		case field.Type == zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok {
+				redacted[i] = zap.String(field.Key, c.redactor.String(err.Error()))
+				continue
+			}
+			redacted[i] = field
+		default:
+			redacted[i] = field
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,64 @@
+package lib
+
+import (
+	"fmt"
+	"observability-demo/lib/redact"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LoadRedactor creates the redactor used by GetTracer and CreateProductionLogger.
+// The defaults are extended by the comma separated REDACT_KEYS, REDACT_QUERY_PARAMS and REDACT_PATTERNS,
+// a comma inside a pattern has to be written as \x2c. The limits use the standard OTEL_* variables.
+// It returns nil if REDACTION is false.
+var LoadRedactor = sync.OnceValues(func() (*redact.Redactor, error) {
+	enabled, err := strconv.ParseBool(GetEnv("REDACTION", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDACTION: %w", err)
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	cfg := redact.DefaultConfig()
+	cfg.Keys = append(cfg.Keys, SplitList(GetEnv("REDACT_KEYS", ""))...)
+	cfg.QueryParams = append(cfg.QueryParams, SplitList(GetEnv("REDACT_QUERY_PARAMS", ""))...)
+	for _, pattern := range SplitList(GetEnv("REDACT_PATTERNS", "")) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDACT_PATTERNS: %w", err)
+		}
+		cfg.Patterns = append(cfg.Patterns, re)
+	}
+
+	limits := map[string]*int{
+		"OTEL_SPAN_ATTRIBUTE_COUNT_LIMIT":   &cfg.MaxAttributes,
+		"OTEL_SPAN_EVENT_COUNT_LIMIT":       &cfg.MaxEvents,
+		"OTEL_ATTRIBUTE_VALUE_LENGTH_LIMIT": &cfg.MaxValueLength,
+	}
+	for env, limit := range limits {
+		value := GetEnv(env, "")
+		if value == "" {
+			continue
+		}
+		if *limit, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", env, err)
+		}
+	}
+
+	return redact.New(cfg), nil
+})
+
+// SplitList splits a comma separated list and drops empty items.
+func SplitList(s string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
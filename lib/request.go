@@ -0,0 +1,204 @@
+package lib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// requestIDMetadata is the gRPC metadata key of the request ID, metadata keys are lower case.
+	requestIDMetadata = "x-request-id"
+
+	maxRequestIDLength = 128
+)
+
+type requestContextKey struct{}
+
+// requestScope is stored in the context of every request passing the request logging middleware.
+type requestScope struct {
+	id     string
+	fields []any
+	log    *zap.SugaredLogger
+}
+
+// RequestID returns the ID of the current request, or an empty string outside of a request.
+func RequestID(ctx context.Context) string {
+	if scope, ok := ctx.Value(requestContextKey{}).(*requestScope); ok {
+		return scope.id
+	}
+
+	return ""
+}
+
+// RequestLogger returns the logger of the current request, or a no-op logger outside of a request.
+func RequestLogger(ctx context.Context) *zap.SugaredLogger {
+	if scope, ok := ctx.Value(requestContextKey{}).(*requestScope); ok {
+		return scope.log
+	}
+
+	return zap.NewNop().Sugar()
+}
+
+// ContextLogger adds the fields of the current request and its tenant to log, so components can keep
+// their own logger and still log lines which can be grouped by request.
+func ContextLogger(ctx context.Context, log *zap.SugaredLogger) *zap.SugaredLogger {
+	if scope, ok := ctx.Value(requestContextKey{}).(*requestScope); ok {
+		log = log.With(scope.fields...)
+	}
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		log = log.With("tenant", tenant)
+	}
+
+	return log
+}
+
+// SetRequestID sets the request ID header of an outbound request to the ID of the current request.
+func SetRequestID(ctx context.Context, header http.Header) {
+	if id := RequestID(ctx); id != "" {
+		header.Set(RequestIDHeader, id)
+	}
+}
+
+// withRequestScope stores the request ID and the request-scoped logger in ctx.
+func withRequestScope(ctx context.Context, log *zap.SugaredLogger, id string, fields ...any) context.Context {
+	fields = append([]any{"request_id", id}, fields...)
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		fields = append(fields, "trace_id", spanCtx.TraceID().String(), "span_id", spanCtx.SpanID().String())
+	}
+	fields = append(fields, baggageFields(ctx)...)
+
+	return context.WithValue(ctx, requestContextKey{}, &requestScope{
+		id:     id,
+		fields: fields,
+		log:    log.With(fields...),
+	})
+}
+
+// requestID accepts the ID of the caller if it is sane, otherwise it creates a new one.
+func requestID(id string) string {
+	if id != "" && len(id) <= maxRequestIDLength && isPrintableASCII(id) {
+		return id
+	}
+
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
+
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '!' || s[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RequestLogging assigns or accepts the X-Request-ID of a request, returns it to the caller,
+// stores the request-scoped logger in the context and writes one access log line per request.
+// It has to be wrapped by the ServeMux to know the route and by the otelhttp handler to know the trace.
+func RequestLogging(log *zap.SugaredLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestID(r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := withRequestScope(r.Context(), log, id,
+			"route", r.Pattern,
+			"method", r.Method,
+			"remote_addr", r.RemoteAddr,
+		)
+
+		snoop := httpsnoop.CaptureMetrics(next, w, r.WithContext(ctx))
+
+		RequestLogger(ctx).Infow("request completed",
+			"path", r.URL.Path,
+			"status", snoop.Code,
+			"duration", snoop.Duration.String(),
+			"bytes", snoop.Written,
+		)
+	})
+}
+
+// RequestIDUnaryInterceptor is the gRPC counterpart of RequestLogging.
+func RequestIDUnaryInterceptor(log *zap.SugaredLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id := incomingRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+
+		ctx = withRequestScope(ctx, log, id, "route", info.FullMethod)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCompleted(ctx, start, err)
+
+		return resp, err
+	}
+}
+
+// RequestIDStreamInterceptor is RequestIDUnaryInterceptor for streams, the access log is written when the stream ends.
+func RequestIDStreamInterceptor(log *zap.SugaredLogger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := incomingRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(requestIDMetadata, id))
+
+		ctx := withRequestScope(ss.Context(), log, id, "route", info.FullMethod)
+
+		start := time.Now()
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		logCompleted(ctx, start, err)
+
+		return err
+	}
+}
+
+// incomingRequestID accepts the request ID from the metadata of a gRPC call or assigns a new one.
+func incomingRequestID(ctx context.Context) string {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadata); len(values) > 0 {
+			id = values[0]
+		}
+	}
+
+	return requestID(id)
+}
+
+// logCompleted writes the access log of a gRPC call, the error is only added if the call failed.
+func logCompleted(ctx context.Context, start time.Time, err error) {
+	fields := []any{"duration", time.Since(start).String()}
+	if err != nil {
+		fields = append(fields, "error", err)
+	}
+	RequestLogger(ctx).Infow("request completed", fields...)
+}
+
+// serverStream replaces the context of a gRPC stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// OutgoingRequestID adds the ID of the current request to the metadata of an outbound gRPC call.
+func OutgoingRequestID(ctx context.Context) context.Context {
+	if id := RequestID(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, requestIDMetadata, id)
+	}
+
+	return ctx
+}
@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// KeyKey is the key of a key-value operation.
+	KeyKey = attribute.Key("kv.key")
+	// KeyFoundKey tells whether a key-value operation found the key, a miss is not an error.
+	KeyFoundKey = attribute.Key("kv.key.found")
+)
+
+// RecordError records err on span and marks the span as failed.
+// ErrNotFound is an expected outcome instead of a failure, it only sets kv.key.found to false.
+func RecordError(span trace.Span, err error) {
+	if errors.Is(err, ErrNotFound) {
+		span.SetAttributes(KeyFoundKey.Bool(false))
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// HTTPError writes an error response with code and records err on span.
+// Server errors are recorded with RecordError and hide err from the caller. Like the semantic conventions
+// of server spans, client errors only set error.type without failing the span and ErrNotFound only sets kv.key.found.
+func HTTPError(w http.ResponseWriter, span trace.Span, code int, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		span.SetAttributes(KeyFoundKey.Bool(false))
+		http.Error(w, "key not found", code)
+	case code >= http.StatusInternalServerError:
+		RecordError(span, err)
+		span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(code)))
+		http.Error(w, "internal server error", code)
+	default:
+		span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(code)))
+		http.Error(w, err.Error(), code)
+	}
+}
@@ -0,0 +1,85 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"observability-demo/lib/telemetrytest"
+	"testing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+)
+
+func TestRecordError(t *testing.T) {
+	tp, spans := telemetrytest.NewTracerProvider(t)
+	tracer := tp.Tracer("test")
+
+	_, notFound := tracer.Start(context.Background(), "not-found")
+	RecordError(notFound, fmt.Errorf("key foo not found: %w", ErrNotFound))
+	notFound.End()
+
+	_, failed := tracer.Start(context.Background(), "failed")
+	RecordError(failed, errors.New("connection refused"))
+	failed.End()
+
+	// A miss is not a failure.
+	span := telemetrytest.AssertSpan(t, spans, "not-found", KeyFoundKey.Bool(false))
+	telemetrytest.AssertNotError(t, span)
+	if len(span.Events()) != 0 {
+		t.Fatalf("expected no exception event for a miss, got %v", span.Events())
+	}
+
+	span = telemetrytest.FindSpan(telemetrytest.Spans(spans), "failed")
+	telemetrytest.AssertError(t, span, "connection refused")
+	telemetrytest.AssertException(t, span, "connection refused")
+}
+
+func TestHTTPError(t *testing.T) {
+	// Only server errors fail a server span, client errors are recorded as error.type.
+	tests := []struct {
+		name      string
+		code      int
+		err       error
+		body      string
+		errorType string
+		failed    bool
+	}{
+		{name: "not found", code: http.StatusNotFound, err: ErrNotFound, body: "key not found"},
+		{name: "client error", code: http.StatusBadRequest, err: errors.New("missing key"), body: "missing key", errorType: "400"},
+		{name: "server error", code: http.StatusBadGateway, err: errors.New("secret upstream detail"), body: "internal server error", errorType: "502", failed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp, spans := telemetrytest.NewTracerProvider(t)
+			_, span := tp.Tracer("test").Start(context.Background(), "handler")
+			rec := httptest.NewRecorder()
+			HTTPError(rec, span, tt.code, tt.err)
+			span.End()
+
+			if rec.Code != tt.code {
+				t.Fatalf("expected status %d, got %d", tt.code, rec.Code)
+			}
+			if body := rec.Body.String(); body != tt.body+"\n" {
+				t.Fatalf("expected body %q, got %q", tt.body, body)
+			}
+
+			got := telemetrytest.FindSpan(telemetrytest.Spans(spans), "handler")
+			if tt.errorType == "" {
+				telemetrytest.AssertSpan(t, spans, "handler", KeyFoundKey.Bool(false))
+			} else {
+				telemetrytest.AssertSpan(t, spans, "handler", semconv.ErrorTypeKey.String(tt.errorType))
+			}
+			if !tt.failed {
+				telemetrytest.AssertNotError(t, got)
+				if len(got.Events()) != 0 {
+					t.Fatalf("expected no exception event for a %s, got %v", tt.name, got.Events())
+				}
+				return
+			}
+			telemetrytest.AssertError(t, got, tt.err.Error())
+			telemetrytest.AssertException(t, got, tt.err.Error())
+		})
+	}
+}
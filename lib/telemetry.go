@@ -0,0 +1,17 @@
+package lib
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Telemetry is what a service needs to emit logs, traces and metrics.
+// Services use it instead of the global providers, so several services can run in one process.
+type Telemetry struct {
+	Logger         *zap.Logger
+	TracerProvider trace.TracerProvider
+	Registry       *prometheus.Registry
+	// Baggage is optional, nil lifts no baggage members.
+	Baggage *Baggage
+}
@@ -0,0 +1,234 @@
+package telemetrytest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// FindSpan returns the first span with the given name, or nil.
+func FindSpan(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	return nil
+}
+
+// AssertSpan fails the test unless a span with the given name exists which has at least the given attributes.
+func AssertSpan(t testing.TB, e *tracetest.InMemoryExporter, name string, attrs ...attribute.KeyValue) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	spans := Spans(e)
+	for _, span := range spans {
+		if span.Name() != name {
+			continue
+		}
+		if missing := missingAttributes(span, attrs); len(missing) == 0 {
+			return span
+		}
+	}
+
+	span := FindSpan(spans, name)
+	if span == nil {
+		t.Fatalf("span %q not found, got spans: %s", name, spanNames(spans))
+		return nil
+	}
+
+	t.Fatalf("span %q is missing attributes %v, got %v", name, missingAttributes(span, attrs), span.Attributes())
+	return nil
+}
+
+// AssertNoSpan fails the test if a span with the given name exists.
+func AssertNoSpan(t testing.TB, e *tracetest.InMemoryExporter, name string) {
+	t.Helper()
+
+	if FindSpan(Spans(e), name) != nil {
+		t.Fatalf("expected no span %q", name)
+	}
+}
+
+// AssertError fails the test unless the span has an error status.
+// If description is set, the status description has to contain it.
+func AssertError(t testing.TB, span sdktrace.ReadOnlySpan, description string) {
+	t.Helper()
+
+	status := span.Status()
+	if status.Code != codes.Error {
+		t.Fatalf("span %q has status %s, expected Error", span.Name(), status.Code)
+	}
+	if !strings.Contains(status.Description, description) {
+		t.Fatalf("span %q has status description %q, expected it to contain %q", span.Name(), status.Description, description)
+	}
+}
+
+// AssertNotError fails the test if the span has an error status.
+func AssertNotError(t testing.TB, span sdktrace.ReadOnlySpan) {
+	t.Helper()
+
+	if status := span.Status(); status.Code == codes.Error {
+		t.Fatalf("span %q has error status: %s", span.Name(), status.Description)
+	}
+}
+
+// AssertException fails the test unless the span recorded an exception event whose message contains message.
+func AssertException(t testing.TB, span sdktrace.ReadOnlySpan, message string) {
+	t.Helper()
+
+	var messages []string
+	for _, event := range span.Events() {
+		if event.Name != semconv.ExceptionEventName {
+			continue
+		}
+		for _, attr := range event.Attributes {
+			if attr.Key == semconv.ExceptionMessageKey {
+				messages = append(messages, attr.Value.AsString())
+				if strings.Contains(attr.Value.AsString(), message) {
+					return
+				}
+			}
+		}
+	}
+
+	t.Fatalf("span %q has no exception event with message %q, got %q", span.Name(), message, messages)
+}
+
+// Tree describes the expected names of a span and its children, children are ordered by start time.
+type Tree struct {
+	Name     string
+	Children []Tree
+}
+
+// String renders the tree with one span per line, indented by depth.
+func (tr Tree) String() string {
+	var b strings.Builder
+	tr.write(&b, 0)
+
+	return b.String()
+}
+
+func (tr Tree) write(b *strings.Builder, depth int) {
+	fmt.Fprintf(b, "%s%s\n", strings.Repeat("  ", depth), tr.Name)
+	for _, child := range tr.Children {
+		child.write(b, depth+1)
+	}
+}
+
+// Trees builds the span trees of all recorded spans. Spans whose parent was not recorded are roots.
+func Trees(spans []sdktrace.ReadOnlySpan) []Tree {
+	ids := make(map[string]struct{}, len(spans))
+	for _, span := range spans {
+		ids[span.SpanContext().SpanID().String()] = struct{}{}
+	}
+
+	children := make(map[string][]sdktrace.ReadOnlySpan)
+	roots := make([]sdktrace.ReadOnlySpan, 0)
+	for _, span := range spans {
+		parent := span.Parent().SpanID().String()
+		if _, ok := ids[parent]; ok && span.Parent().IsValid() {
+			children[parent] = append(children[parent], span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+
+	var build func(span sdktrace.ReadOnlySpan) Tree
+	build = func(span sdktrace.ReadOnlySpan) Tree {
+		kids := children[span.SpanContext().SpanID().String()]
+		sortByStart(kids)
+
+		tree := Tree{Name: span.Name()}
+		for _, kid := range kids {
+			tree.Children = append(tree.Children, build(kid))
+		}
+
+		return tree
+	}
+
+	sortByStart(roots)
+	trees := make([]Tree, 0, len(roots))
+	for _, root := range roots {
+		trees = append(trees, build(root))
+	}
+
+	return trees
+}
+
+// AssertTree fails the test unless the recorded spans form exactly the expected trees.
+func AssertTree(t testing.TB, e *tracetest.InMemoryExporter, want ...Tree) {
+	t.Helper()
+
+	got := Trees(Spans(e))
+	if render(got) != render(want) {
+		t.Fatalf("span trees do not match\ngot:\n%s\nwant:\n%s", render(got), render(want))
+	}
+}
+
+// AssertTraceTree fails the test unless the spans of the trace form exactly the expected trees within timeout.
+// Server spans end after the response was written, so the spans are polled instead of read once.
+func AssertTraceTree(t testing.TB, e *tracetest.InMemoryExporter, traceID trace.TraceID, timeout time.Duration, want ...Tree) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		got := Trees(TraceSpans(e, traceID))
+		if render(got) == render(want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("span trees of trace %s do not match\ngot:\n%s\nwant:\n%s", traceID, render(got), render(want))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func render(trees []Tree) string {
+	var b strings.Builder
+	for _, tree := range trees {
+		b.WriteString(tree.String())
+	}
+
+	return b.String()
+}
+
+func sortByStart(spans []sdktrace.ReadOnlySpan) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime().Before(spans[j].StartTime())
+	})
+}
+
+func missingAttributes(span sdktrace.ReadOnlySpan, want []attribute.KeyValue) []attribute.KeyValue {
+	got := make(map[attribute.Key]attribute.Value, len(span.Attributes()))
+	for _, attr := range span.Attributes() {
+		got[attr.Key] = attr.Value
+	}
+
+	missing := make([]attribute.KeyValue, 0)
+	for _, attr := range want {
+		if value, ok := got[attr.Key]; !ok || value.Type() != attr.Value.Type() || value.Emit() != attr.Value.Emit() {
+			missing = append(missing, attr)
+		}
+	}
+
+	return missing
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+
+	return strings.Join(names, ", ")
+}
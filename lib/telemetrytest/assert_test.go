@@ -0,0 +1,73 @@
+package telemetrytest
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTrees(t *testing.T) {
+	tp, spans := NewTracerProvider(t)
+	tracer := tp.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, first := tracer.Start(ctx, "first")
+	first.End()
+	childCtx, second := tracer.Start(ctx, "second")
+	_, grandchild := tracer.Start(childCtx, "grandchild")
+	grandchild.End()
+	second.End()
+	root.End()
+	_, other := tracer.Start(context.Background(), "other")
+	other.End()
+
+	AssertTree(t, spans,
+		Tree{Name: "root", Children: []Tree{
+			{Name: "first"},
+			{Name: "second", Children: []Tree{{Name: "grandchild"}}},
+		}},
+		Tree{Name: "other"},
+	)
+	if got := TraceSpans(spans, root.SpanContext().TraceID()); len(got) != 4 {
+		t.Fatalf("expected 4 spans in the trace of root, got %d", len(got))
+	}
+}
+
+func TestAssertSpan(t *testing.T) {
+	tp, spans := NewTracerProvider(t)
+
+	_, span := tp.Tracer("test").Start(context.Background(), "op", trace.WithAttributes(
+		attribute.String("kv.key", "foo"),
+		attribute.Bool("kv.key.found", true),
+	))
+	span.SetStatus(codes.Error, "failed to get key foo")
+	span.End()
+
+	got := AssertSpan(t, spans, "op", attribute.String("kv.key", "foo"))
+	AssertError(t, got, "failed to get")
+	AssertNoSpan(t, spans, "missing")
+	if missing := missingAttributes(got, []attribute.KeyValue{attribute.Int("kv.key.found", 1)}); len(missing) != 1 {
+		t.Fatalf("expected an attribute of another type to be missing, got %v", missing)
+	}
+}
+
+func TestExemplars(t *testing.T) {
+	exposition := `# TYPE http_server_requests_total counter
+http_server_requests_total{code="200"} 2.0 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 1.0 1.7e+09
+http_server_requests_total{code="500"} 1.0
+# TYPE http_server_request_duration_seconds histogram
+http_server_request_duration_seconds_bucket{le="0.1"} 1 # {span_id="00f067aa0ba902b7",trace_id="0af7651916cd43dd8448eb211c80319c"} 0.05 1.7e+09
+http_server_request_duration_seconds_count 1
+`
+
+	if got := Exemplars(exposition, "http_server_requests_total"); !slices.Equal(got, []string{"4bf92f3577b34da6a3ce929d0e0e4736"}) {
+		t.Fatalf("unexpected exemplars of the counter: %v", got)
+	}
+	if got := Exemplars(exposition, "http_server_request_duration_seconds"); !slices.Equal(got, []string{"0af7651916cd43dd8448eb211c80319c"}) {
+		t.Fatalf("unexpected exemplars of the histogram: %v", got)
+	}
+}
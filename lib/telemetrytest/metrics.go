@@ -0,0 +1,85 @@
+package telemetrytest
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// openMetricsAccept requests the OpenMetrics format, the Prometheus text format has no exemplars.
+const openMetricsAccept = "application/openmetrics-text; version=1.0.0"
+
+// exemplarTraceID matches the trace ID in the exemplar of a sample, e.g.
+// http_server_requests_total{code="200"} 1.0 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 1.0 1.7e+09
+var exemplarTraceID = regexp.MustCompile(` # \{(?:[^}]*,)?trace_id="([0-9a-f]{32})"`)
+
+// ScrapeOpenMetrics fetches the metrics from url in the OpenMetrics format.
+func ScrapeOpenMetrics(t testing.TB, url string) string {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	req.Header.Set("Accept", openMetricsAccept)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to scrape %s: %s", url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %s", err)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/openmetrics-text") {
+		t.Fatalf("%s did not respond with OpenMetrics, got %q", url, resp.Header.Get("Content-Type"))
+	}
+
+	return string(body)
+}
+
+// Exemplars returns the trace IDs of the exemplars of a metric in an OpenMetrics exposition.
+// The samples of a histogram are matched by the metric name without the _bucket suffix.
+func Exemplars(exposition, metric string) []string {
+	var traceIDs []string
+	for _, line := range strings.Split(exposition, "\n") {
+		name, _, _ := strings.Cut(line, "{")
+		name, _, _ = strings.Cut(name, " ")
+		if name != metric && name != metric+"_bucket" {
+			continue
+		}
+		if match := exemplarTraceID.FindStringSubmatch(line); match != nil {
+			traceIDs = append(traceIDs, match[1])
+		}
+	}
+
+	return traceIDs
+}
+
+// AssertExemplar fails the test unless the metric has an exemplar with the trace ID.
+func AssertExemplar(t testing.TB, exposition, metric string, traceID trace.TraceID) {
+	t.Helper()
+
+	traceIDs := Exemplars(exposition, metric)
+	if !slices.Contains(traceIDs, traceID.String()) {
+		t.Fatalf("metric %s has no exemplar with trace ID %s, got %v", metric, traceID, traceIDs)
+	}
+}
+
+// AssertNoExemplar fails the test if the metric has an exemplar with the trace ID, e.g. of an unsampled trace.
+func AssertNoExemplar(t testing.TB, exposition, metric string, traceID trace.TraceID) {
+	t.Helper()
+
+	if slices.Contains(Exemplars(exposition, metric), traceID.String()) {
+		t.Fatalf("metric %s has an exemplar with trace ID %s", metric, traceID)
+	}
+}
@@ -0,0 +1,65 @@
+// Package telemetrytest provides assertions on the spans and metrics a component creates.
+// The spans are captured with the in-memory exporter of the SDK.
+package telemetrytest
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracerProvider returns a provider which samples every span and exports it synchronously when it ends,
+// so spans are visible to assertions without flushing.
+func NewTracerProvider(t testing.TB) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSyncer(exporter),
+	)
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+	})
+
+	return tp, exporter
+}
+
+// NewServiceTracerProvider returns a provider like NewTracerProvider which exports to a shared exporter
+// and sets service.name, so several services can run in one test.
+func NewServiceTracerProvider(t testing.TB, exporter *tracetest.InMemoryExporter, service string) *sdktrace.TracerProvider {
+	t.Helper()
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+	})
+
+	return tp
+}
+
+// Spans returns all ended spans in the order they ended.
+func Spans(e *tracetest.InMemoryExporter) []sdktrace.ReadOnlySpan {
+	return e.GetSpans().Snapshots()
+}
+
+// TraceSpans returns the ended spans of a single trace.
+func TraceSpans(e *tracetest.InMemoryExporter, traceID trace.TraceID) []sdktrace.ReadOnlySpan {
+	spans := make([]sdktrace.ReadOnlySpan, 0)
+	for _, span := range Spans(e) {
+		if span.SpanContext().TraceID() == traceID {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
@@ -0,0 +1,123 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// TenantHeader selects the namespace of a request, it takes precedence over the tenant baggage member.
+	TenantHeader = "X-Tenant"
+	// tenantMetadata is the gRPC metadata key of the tenant.
+	tenantMetadata = "x-tenant"
+	// TenantBaggageKey is the baggage member which selects the namespace if the header is missing.
+	TenantBaggageKey = "tenant"
+	// DefaultTenant is the namespace of requests without tenant.
+	DefaultTenant = "default"
+
+	// TenantKey is the tenant of a request.
+	TenantKey = attribute.Key("tenant.id")
+)
+
+var (
+	// ErrInvalidTenant is returned for tenant names which can't be used as namespace.
+	ErrInvalidTenant = errors.New("invalid tenant")
+	// ErrUnknownTenant is returned for tenants which are not allowed.
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrQuotaExceeded is returned when a write would exceed the quota of the namespace.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type tenantContextKey struct{}
+
+// WithTenant stores the tenant of the current request in ctx.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// Tenant returns the tenant of the current request, or DefaultTenant.
+func Tenant(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		return tenant
+	}
+
+	return DefaultTenant
+}
+
+// ResolveTenant returns the tenant of a request from the X-Tenant header or the tenant baggage member.
+// If allowed is not empty, other tenants are rejected with ErrUnknownTenant.
+func ResolveTenant(r *http.Request, allowed []string) (string, error) {
+	return resolveTenant(r.Context(), r.Header.Get(TenantHeader), allowed)
+}
+
+// ResolveTenantGRPC is ResolveTenant for the x-tenant metadata of a gRPC call.
+func ResolveTenantGRPC(ctx context.Context, allowed []string) (string, error) {
+	var tenant string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(tenantMetadata); len(values) > 0 {
+			tenant = values[0]
+		}
+	}
+
+	return resolveTenant(ctx, tenant, allowed)
+}
+
+func resolveTenant(ctx context.Context, tenant string, allowed []string) (string, error) {
+	if tenant == "" {
+		tenant = baggage.FromContext(ctx).Member(TenantBaggageKey).Value()
+	}
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+
+	if !tenantPattern.MatchString(tenant) {
+		return "", fmt.Errorf("%w %q, use lower case letters, digits, - and _", ErrInvalidTenant, tenant)
+	}
+	if len(allowed) > 0 && !slices.Contains(allowed, tenant) {
+		return "", fmt.Errorf("%w %q", ErrUnknownTenant, tenant)
+	}
+
+	return tenant, nil
+}
+
+// SetTenant sets the tenant header of an outbound request to the tenant of the current request.
+func SetTenant(ctx context.Context, header http.Header) {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		header.Set(TenantHeader, tenant)
+	}
+}
+
+// OutgoingTenant adds the tenant of the current request to the metadata of an outbound gRPC call.
+func OutgoingTenant(ctx context.Context) context.Context {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		return metadata.AppendToOutgoingContext(ctx, tenantMetadata, tenant)
+	}
+
+	return ctx
+}
+
+// HTTPStatus returns the status code for the errors of the store, any other error is an internal server error.
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidTenant):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnknownTenant):
+		return http.StatusForbidden
+	case errors.Is(err, ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,250 @@
+package traceviewer
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const searchLimit = 100
+
+var funcs = template.FuncMap{
+	"ms": func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', 2, 64) + "ms"
+	},
+	"clock": func(t time.Time) string {
+		return t.Format("15:04:05.000")
+	},
+}
+
+var searchTmpl = template.Must(template.New("search").Funcs(funcs).Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Traces</title>
+	<style>
+		body { font-family: sans-serif; }
+		table { border-collapse: collapse; }
+		td, th { padding: 2px 8px; text-align: left; }
+		.error { color: #c00; }
+	</style>
+</head>
+<body>
+	<h1>Traces</h1>
+	<form method="GET" action="{{.Prefix}}">
+		<label for="service">Service:</label>
+		<select id="service" name="service">
+			<option value="">any</option>
+			{{range .Services}}<option value="{{.}}" {{if eq . $.Query.Service}}selected{{end}}>{{.}}</option>{{end}}
+		</select>
+		<label for="name">Span name:</label>
+		<input type="text" id="name" name="name" value="{{.Query.SpanName}}">
+		<label for="min">Min duration:</label>
+		<input type="text" id="min" name="min" placeholder="e.g. 5ms" value="{{.Min}}">
+		<label for="max">Max duration:</label>
+		<input type="text" id="max" name="max" placeholder="e.g. 1s" value="{{.Max}}">
+		<label for="status">Status:</label>
+		<select id="status" name="status">
+			<option value="">any</option>
+			{{range .Statuses}}<option value="{{.}}" {{if eq . $.Query.Status}}selected{{end}}>{{.}}</option>{{end}}
+		</select>
+		<button type="submit">Search</button>
+	</form>
+	<table>
+		<tr><th>Start</th><th>Trace ID</th><th>Root span</th><th>Services</th><th>Spans</th><th>Duration</th></tr>
+		{{range .Traces}}
+		<tr {{if .HasError}}class="error"{{end}}>
+			<td>{{clock .Start}}</td>
+			<td><a href="{{$.Prefix}}{{.ID}}">{{.ID}}</a></td>
+			<td>{{with .Root}}{{.Service}}: {{.Name}}{{end}}</td>
+			<td>{{range .Services}}{{.}} {{end}}</td>
+			<td>{{len .Spans}}</td>
+			<td>{{ms .Duration}}</td>
+		</tr>
+		{{else}}
+		<tr><td colspan="6">No traces found.</td></tr>
+		{{end}}
+	</table>
+</body>
+</html>
+`))
+
+var traceTmpl = template.Must(template.New("trace").Funcs(funcs).Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Trace {{.Trace.ID}}</title>
+	<style>
+		body { font-family: sans-serif; }
+		.row { display: flex; align-items: center; border-bottom: 1px solid #eee; }
+		.label { width: 35%; overflow: hidden; white-space: nowrap; }
+		.timeline { width: 65%; position: relative; height: 1.2em; }
+		.bar { position: absolute; height: 100%; background: #4a90d9; min-width: 1px; }
+		.error .bar { background: #c00; }
+		.error .label { color: #c00; }
+		details { margin-left: 2em; font-size: 0.9em; }
+		td { padding: 1px 8px; }
+	</style>
+</head>
+<body>
+	<p><a href="{{.Prefix}}">&larr; all traces</a></p>
+	<h1>Trace {{.Trace.ID}}</h1>
+	<p>{{clock .Trace.Start}}, {{ms .Trace.Duration}}, {{len .Trace.Spans}} spans</p>
+	{{range .Rows}}
+	<div class="row {{if .Span.IsError}}error{{end}}">
+		<div class="label" style="padding-left: {{.Indent}}em">{{.Span.Service}}: {{.Span.Name}} ({{ms .Span.Duration}})</div>
+		<div class="timeline"><div class="bar" style="left: {{.Offset}}%; width: {{.Width}}%"></div></div>
+	</div>
+	<details>
+		<summary>details</summary>
+		<table>
+			<tr><td>span ID</td><td>{{.Span.SpanID}}</td></tr>
+			<tr><td>parent span ID</td><td>{{.Span.ParentSpanID}}</td></tr>
+			<tr><td>kind</td><td>{{.Span.Kind}}</td></tr>
+			<tr><td>scope</td><td>{{.Span.Scope}}</td></tr>
+			<tr><td>status</td><td>{{.Span.StatusCode}} {{.Span.StatusMessage}}</td></tr>
+			{{range .Span.Attributes}}<tr><td>{{.Key}}</td><td>{{.Value}}</td></tr>{{end}}
+		</table>
+		{{if .Span.Events}}
+		<h4>Events</h4>
+		<table>
+			{{range .Span.Events}}
+			<tr><td>{{clock .Time}}</td><td>{{.Name}}</td><td>{{range .Attributes}}{{.Key}}={{.Value}} {{end}}</td></tr>
+			{{end}}
+		</table>
+		{{end}}
+		<h4>Resource</h4>
+		<table>
+			{{range .Span.ResourceAttributes}}<tr><td>{{.Key}}</td><td>{{.Value}}</td></tr>{{end}}
+		</table>
+	</details>
+	{{end}}
+</body>
+</html>
+`))
+
+// row is a single line of the waterfall view.
+type row struct {
+	Span   *Span
+	Indent int
+	// Offset and Width are percentages of the trace duration.
+	Offset float64
+	Width  float64
+}
+
+// NewHandler serves the search page on prefix and the waterfall of a single trace on prefix followed by the trace ID.
+// prefix has to end with a slash.
+func NewHandler(store *Store, prefix string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+prefix+"{$}", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		q := Query{
+			Service:  params.Get("service"),
+			SpanName: params.Get("name"),
+			Status:   params.Get("status"),
+			Limit:    searchLimit,
+		}
+
+		var err error
+		if min := params.Get("min"); min != "" {
+			if q.MinDuration, err = time.ParseDuration(min); err != nil {
+				http.Error(w, "invalid min duration", http.StatusBadRequest)
+				return
+			}
+		}
+		if max := params.Get("max"); max != "" {
+			if q.MaxDuration, err = time.ParseDuration(max); err != nil {
+				http.Error(w, "invalid max duration", http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = searchTmpl.Execute(w, map[string]any{
+			"Prefix":   prefix,
+			"Query":    q,
+			"Min":      params.Get("min"),
+			"Max":      params.Get("max"),
+			"Services": store.Services(),
+			"Statuses": []string{"unset", "ok", "error"},
+			"Traces":   store.Search(q),
+		})
+		if err != nil {
+			http.Error(w, "failed to render template", http.StatusInternalServerError)
+		}
+	})
+
+	mux.HandleFunc("GET "+prefix+"{id}", func(w http.ResponseWriter, r *http.Request) {
+		t, ok := store.Get(r.PathValue("id"))
+		if !ok {
+			http.Error(w, "trace not found", http.StatusNotFound)
+			return
+		}
+
+		err := traceTmpl.Execute(w, map[string]any{
+			"Prefix": prefix,
+			"Trace":  t,
+			"Rows":   waterfall(t),
+		})
+		if err != nil {
+			http.Error(w, "failed to render template", http.StatusInternalServerError)
+		}
+	})
+
+	return mux
+}
+
+// waterfall orders the spans depth-first, children sorted by start time.
+func waterfall(t *Trace) []row {
+	start := t.Start()
+	total := float64(t.Duration())
+	if total <= 0 {
+		total = 1
+	}
+
+	ids := make(map[string]struct{}, len(t.Spans))
+	for _, span := range t.Spans {
+		ids[span.SpanID] = struct{}{}
+	}
+
+	children := make(map[string][]*Span)
+	roots := make([]*Span, 0)
+	for _, span := range t.Spans {
+		if _, ok := ids[span.ParentSpanID]; ok {
+			children[span.ParentSpanID] = append(children[span.ParentSpanID], span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+
+	byStart := func(spans []*Span) {
+		sort.Slice(spans, func(i, j int) bool {
+			return spans[i].Start.Before(spans[j].Start)
+		})
+	}
+
+	rows := make([]row, 0, len(t.Spans))
+	var visit func(span *Span, depth int)
+	visit = func(span *Span, depth int) {
+		rows = append(rows, row{
+			Span:   span,
+			Indent: depth,
+			Offset: float64(span.Start.Sub(start)) / total * 100,
+			Width:  float64(span.Duration()) / total * 100,
+		})
+
+		byStart(children[span.SpanID])
+		for _, child := range children[span.SpanID] {
+			visit(child, depth+1)
+		}
+	}
+
+	byStart(roots)
+	for _, root := range roots {
+		visit(root, 0)
+	}
+
+	return rows
+}
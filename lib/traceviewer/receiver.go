@@ -0,0 +1,201 @@
+package traceviewer
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+
+	maxRequestSize = 16 << 20
+)
+
+// Receiver accepts OTLP/HTTP trace exports on /v1/traces and adds the spans to the store.
+type Receiver struct {
+	store *Store
+}
+
+func NewReceiver(store *Store) *Receiver {
+	return &Receiver{store: store}
+}
+
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body := io.Reader(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, "invalid gzip body", http.StatusBadRequest)
+			return
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		body = gz
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	isJSON := strings.HasPrefix(contentType, contentTypeJSON)
+
+	var req coltracepb.ExportTraceServiceRequest
+	if isJSON {
+		err = protojson.Unmarshal(raw, &req)
+	} else {
+		err = proto.Unmarshal(raw, &req)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode export request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	rc.store.Add(convert(&req))
+
+	var resp []byte
+	if isJSON {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		resp, err = protojson.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	} else {
+		w.Header().Set("Content-Type", contentTypeProtobuf)
+		resp, err = proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	}
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(resp)
+}
+
+func convert(req *coltracepb.ExportTraceServiceRequest) []*Span {
+	spans := make([]*Span, 0)
+	for _, rs := range req.GetResourceSpans() {
+		resourceAttributes := convertAttributes(rs.GetResource().GetAttributes())
+		service := "unknown"
+		for _, attr := range resourceAttributes {
+			if attr.Key == "service.name" {
+				service = attr.Value
+			}
+		}
+
+		for _, ss := range rs.GetScopeSpans() {
+			for _, s := range ss.GetSpans() {
+				span := &Span{
+					TraceID:            hex.EncodeToString(s.GetTraceId()),
+					SpanID:             hex.EncodeToString(s.GetSpanId()),
+					ParentSpanID:       hex.EncodeToString(s.GetParentSpanId()),
+					Name:               s.GetName(),
+					Service:            service,
+					Scope:              ss.GetScope().GetName(),
+					Kind:               spanKind(s.GetKind()),
+					Start:              time.Unix(0, int64(s.GetStartTimeUnixNano())),
+					End:                time.Unix(0, int64(s.GetEndTimeUnixNano())),
+					StatusCode:         statusCode(s.GetStatus().GetCode()),
+					StatusMessage:      s.GetStatus().GetMessage(),
+					Attributes:         convertAttributes(s.GetAttributes()),
+					ResourceAttributes: resourceAttributes,
+				}
+				for _, e := range s.GetEvents() {
+					span.Events = append(span.Events, Event{
+						Name:       e.GetName(),
+						Time:       time.Unix(0, int64(e.GetTimeUnixNano())),
+						Attributes: convertAttributes(e.GetAttributes()),
+					})
+				}
+				spans = append(spans, span)
+			}
+		}
+	}
+
+	return spans
+}
+
+func convertAttributes(kvs []*commonpb.KeyValue) []Attribute {
+	attributes := make([]Attribute, 0, len(kvs))
+	for _, kv := range kvs {
+		attributes = append(attributes, Attribute{Key: kv.GetKey(), Value: anyValue(kv.GetValue())})
+	}
+
+	return attributes
+}
+
+func anyValue(v *commonpb.AnyValue) string {
+	switch v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.GetStringValue()
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.GetBoolValue())
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.GetIntValue(), 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.GetDoubleValue(), 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return hex.EncodeToString(v.GetBytesValue())
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]string, 0, len(v.GetArrayValue().GetValues()))
+		for _, value := range v.GetArrayValue().GetValues() {
+			values = append(values, anyValue(value))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case *commonpb.AnyValue_KvlistValue:
+		values := make([]string, 0, len(v.GetKvlistValue().GetValues()))
+		for _, kv := range v.GetKvlistValue().GetValues() {
+			values = append(values, kv.GetKey()+"="+anyValue(kv.GetValue()))
+		}
+		return "{" + strings.Join(values, ", ") + "}"
+	default:
+		return ""
+	}
+}
+
+func spanKind(kind tracepb.Span_SpanKind) string {
+	switch kind {
+	case tracepb.Span_SPAN_KIND_INTERNAL:
+		return "internal"
+	case tracepb.Span_SPAN_KIND_SERVER:
+		return "server"
+	case tracepb.Span_SPAN_KIND_CLIENT:
+		return "client"
+	case tracepb.Span_SPAN_KIND_PRODUCER:
+		return "producer"
+	case tracepb.Span_SPAN_KIND_CONSUMER:
+		return "consumer"
+	default:
+		return "unspecified"
+	}
+}
+
+func statusCode(code tracepb.Status_StatusCode) string {
+	switch code {
+	case tracepb.Status_STATUS_CODE_OK:
+		return "Ok"
+	case tracepb.Status_STATUS_CODE_ERROR:
+		return "Error"
+	default:
+		return "Unset"
+	}
+}
@@ -0,0 +1,271 @@
+package traceviewer
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []Attribute
+}
+
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+
+	Name    string
+	Service string
+	Scope   string
+	Kind    string
+
+	Start time.Time
+	End   time.Time
+
+	// StatusCode is one of "Unset", "Ok" or "Error".
+	StatusCode    string
+	StatusMessage string
+
+	Attributes         []Attribute
+	ResourceAttributes []Attribute
+	Events             []Event
+}
+
+func (s *Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+func (s *Span) IsError() bool {
+	return s.StatusCode == "Error"
+}
+
+type Trace struct {
+	ID    string
+	Spans []*Span
+}
+
+// Root returns the span without a parent in this trace, or the earliest span if the root is missing.
+func (t *Trace) Root() *Span {
+	ids := make(map[string]struct{}, len(t.Spans))
+	for _, span := range t.Spans {
+		ids[span.SpanID] = struct{}{}
+	}
+
+	var root *Span
+	for _, span := range t.Spans {
+		if _, ok := ids[span.ParentSpanID]; ok {
+			continue
+		}
+		if root == nil || span.Start.Before(root.Start) {
+			root = span
+		}
+	}
+
+	return root
+}
+
+func (t *Trace) Start() time.Time {
+	var start time.Time
+	for _, span := range t.Spans {
+		if start.IsZero() || span.Start.Before(start) {
+			start = span.Start
+		}
+	}
+
+	return start
+}
+
+func (t *Trace) End() time.Time {
+	var end time.Time
+	for _, span := range t.Spans {
+		if span.End.After(end) {
+			end = span.End
+		}
+	}
+
+	return end
+}
+
+func (t *Trace) Duration() time.Duration {
+	return t.End().Sub(t.Start())
+}
+
+// Services returns the sorted names of all services taking part in this trace.
+func (t *Trace) Services() []string {
+	seen := make(map[string]struct{})
+	for _, span := range t.Spans {
+		seen[span.Service] = struct{}{}
+	}
+
+	services := make([]string, 0, len(seen))
+	for service := range seen {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	return services
+}
+
+func (t *Trace) HasError() bool {
+	for _, span := range t.Spans {
+		if span.IsError() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Query filters traces. A trace matches if at least one of its spans matches all set fields.
+type Query struct {
+	Service     string
+	SpanName    string
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	// Status is one of "", "unset", "ok" or "error".
+	Status string
+	Limit  int
+}
+
+func (q Query) matches(span *Span) bool {
+	if q.Service != "" && span.Service != q.Service {
+		return false
+	}
+	if q.SpanName != "" && !strings.Contains(span.Name, q.SpanName) {
+		return false
+	}
+	if q.MinDuration > 0 && span.Duration() < q.MinDuration {
+		return false
+	}
+	if q.MaxDuration > 0 && span.Duration() > q.MaxDuration {
+		return false
+	}
+	if q.Status != "" && !strings.EqualFold(span.StatusCode, q.Status) {
+		return false
+	}
+
+	return true
+}
+
+// Store keeps the most recent traces in a ring buffer.
+type Store struct {
+	mu       sync.RWMutex
+	capacity int
+	traces   map[string]*Trace
+	// ring holds trace IDs in insertion order, next points to the oldest entry once the ring is full.
+	ring []string
+	next int
+}
+
+func NewStore(capacity int) *Store {
+	return &Store{
+		capacity: capacity,
+		traces:   make(map[string]*Trace, capacity),
+		ring:     make([]string, 0, capacity),
+	}
+}
+
+// Add stores the spans, evicting the oldest traces if the store is full.
+func (s *Store) Add(spans []*Span) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, span := range spans {
+		t, ok := s.traces[span.TraceID]
+		if !ok {
+			t = &Trace{ID: span.TraceID}
+			s.insert(t)
+		}
+		t.Spans = append(t.Spans, span)
+	}
+}
+
+// insert must be called with s.mu held.
+func (s *Store) insert(t *Trace) {
+	if len(s.ring) < s.capacity {
+		s.ring = append(s.ring, t.ID)
+		s.traces[t.ID] = t
+		return
+	}
+
+	delete(s.traces, s.ring[s.next])
+	s.ring[s.next] = t.ID
+	s.traces[t.ID] = t
+	s.next = (s.next + 1) % s.capacity
+}
+
+func (s *Store) Get(traceID string) (*Trace, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.traces[traceID]
+	if !ok {
+		return nil, false
+	}
+
+	return t.clone(), true
+}
+
+// Search returns the matching traces, newest first.
+func (s *Store) Search(q Query) []*Trace {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]*Trace, 0)
+	for _, t := range s.traces {
+		for _, span := range t.Spans {
+			if q.matches(span) {
+				results = append(results, t.clone())
+				break
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Start().After(results[j].Start())
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+
+	return results
+}
+
+// Services returns the sorted names of all services seen in the stored traces.
+func (s *Store) Services() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	for _, t := range s.traces {
+		for _, span := range t.Spans {
+			seen[span.Service] = struct{}{}
+		}
+	}
+
+	services := make([]string, 0, len(seen))
+	for service := range seen {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	return services
+}
+
+// clone copies the span list so callers can read it without holding the lock.
+// Spans themselves are never modified after being added.
+func (t *Trace) clone() *Trace {
+	spans := make([]*Span, len(t.Spans))
+	copy(spans, t.Spans)
+
+	return &Trace{ID: t.ID, Spans: spans}
+}
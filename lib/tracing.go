@@ -2,8 +2,11 @@ package lib
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"observability-demo/lib/redact"
 	"os"
+	"sync/atomic"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
@@ -22,28 +25,58 @@ const (
 func GetTracer(ctx context.Context, target TraceExportTarget) (*sdktrace.TracerProvider, error) {
 	var exporter sdktrace.SpanExporter
 	var err error
+	settings := TracingSettings{
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		Propagators: []string{"tracecontext", "baggage"},
+	}
 
 	switch target {
 	case Stdout:
+		settings.Exporter = "stdout"
 		exporter, err = stdout.New(stdout.WithPrettyPrint())
 		if err != nil {
 			return nil, err
 		}
 	case Backend:
-		exporter, err = otlptracehttp.New(
-			ctx,
-			otlptracehttp.WithInsecure(),
-			otlptracehttp.WithEndpoint("127.0.0.1:4318"),
-		)
+		settings.Exporter = "otlp/http"
+		settings.Endpoint = otlpEndpoint()
+		// The exporter reads the standard OTEL_EXPORTER_OTLP_* variables,
+		// only fall back to the local Tempo if no endpoint is configured.
+		var opts []otlptracehttp.Option
+		if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+			opts = append(opts,
+				otlptracehttp.WithInsecure(),
+				otlptracehttp.WithEndpoint("127.0.0.1:4318"),
+			)
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create trace exporter: %w", err)
 		}
 
 	}
 
+	monitored := &monitoredExporter{SpanExporter: exporter}
+	traceExporter.Store(monitored)
+
+	redactor, err := LoadRedactor()
+	if err != nil {
+		return nil, err
+	}
+	var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(monitored)
+	if redactor != nil {
+		// Redact before batching, so secrets never reach the exporter.
+		processor = redact.NewSpanProcessor(processor, redactor)
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	settings.Sampler = sampler.Description()
+	settings.Redaction = redactor != nil
+	tracingSettings.Store(&settings)
+
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithSpanProcessor(processor),
 	)
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(
@@ -56,6 +89,77 @@ func GetTracer(ctx context.Context, target TraceExportTarget) (*sdktrace.TracerP
 	return tp, nil
 }
 
+// TracingSettings describe the tracer provider created by GetTracer.
+type TracingSettings struct {
+	ServiceName string   `json:"service_name"`
+	Exporter    string   `json:"exporter"`
+	Endpoint    string   `json:"endpoint,omitempty"`
+	Sampler     string   `json:"sampler"`
+	Propagators []string `json:"propagators"`
+	// Redaction tells whether spans are redacted before they are exported.
+	Redaction bool `json:"redaction"`
+}
+
+var tracingSettings atomic.Pointer[TracingSettings]
+
+// CurrentTracingSettings returns the settings of the last tracer provider created by GetTracer.
+func CurrentTracingSettings() (TracingSettings, bool) {
+	settings := tracingSettings.Load()
+	if settings == nil {
+		return TracingSettings{}, false
+	}
+
+	return *settings, true
+}
+
+// otlpEndpoint returns the endpoint the OTLP exporter sends the spans to.
+func otlpEndpoint() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+
+	return "http://127.0.0.1:4318"
+}
+
+// monitoredExporter remembers the result of the last export for the health check.
+type monitoredExporter struct {
+	sdktrace.SpanExporter
+
+	lastErr atomic.Pointer[error]
+}
+
+func (e *monitoredExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	e.lastErr.Store(&err)
+
+	return err
+}
+
+var traceExporter atomic.Pointer[monitoredExporter]
+
+// CheckTraceExporter is a health check which fails if the last export of the exporter created by GetTracer failed.
+func CheckTraceExporter(_ context.Context) error {
+	exporter := traceExporter.Load()
+	if exporter == nil {
+		return errors.New("no trace exporter configured")
+	}
+
+	if err := exporter.lastErr.Load(); err != nil && *err != nil {
+		return fmt.Errorf("last export failed: %w", *err)
+	}
+
+	return nil
+}
+
 func SetRuntimeSettings(serviceName string) {
 	_ = os.Setenv("OTEL_SERVICE_NAME", serviceName)
+
+	// Make the HTTP instrumentation emit the stable semantic conventions (http.request.method, url.full, ...)
+	// next to the old ones. It has to be set before any instrumented handler or transport is created.
+	if os.Getenv("OTEL_SEMCONV_STABILITY_OPT_IN") == "" {
+		_ = os.Setenv("OTEL_SEMCONV_STABILITY_OPT_IN", "http/dup")
+	}
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"observability-demo/internal/loadgen"
+	"observability-demo/lib"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	if err := run(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context) error {
+	var cfg loadgen.Config
+	var target, distribution string
+	flag.StringVar(&target, "target", string(loadgen.TargetService1), "service to send the requests to: service-1 or ui")
+	flag.StringVar(&cfg.Address, "address", "", "base URL of the target (default depends on -target)")
+	flag.Float64Var(&cfg.GetRatio, "get-ratio", 0.9, "share of get requests between 0 and 1, the rest are sets")
+	flag.IntVar(&cfg.Keys, "keys", 1000, "number of distinct keys")
+	flag.StringVar(&distribution, "distribution", string(loadgen.Uniform), "key distribution: uniform or zipf")
+	flag.Float64Var(&cfg.ZipfS, "zipf-s", 1.1, "exponent of the zipf distribution, has to be greater than 1")
+	flag.IntVar(&cfg.Concurrency, "concurrency", 10, "number of concurrent workers")
+	flag.Float64Var(&cfg.Rate, "rate", 0, "requests per second over all workers, 0 is unlimited")
+	flag.DurationVar(&cfg.Duration, "duration", 30*time.Second, "duration of the run")
+	flag.DurationVar(&cfg.Timeout, "timeout", 5*time.Second, "timeout of a single request")
+	flag.StringVar(&cfg.APIKey, "api-key", "", "API key for service-1, if it requires authentication")
+	flag.Parse()
+
+	cfg.Target = loadgen.Target(target)
+	cfg.Distribution = loadgen.Distribution(distribution)
+	if cfg.Address == "" {
+		cfg.Address = loadgen.DefaultAddress(cfg.Target)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	lib.SetRuntimeSettings("loadgen")
+	log := lib.CreateProductionLogger("loadgen")
+	logs := log.Sugar()
+	defer func() {
+		_ = log.Sync()
+	}()
+
+	traceProvider, err := lib.GetTracer(context.Background(), lib.Backend)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := lib.ShutdownTracerProvider(traceProvider)(ctx); err != nil {
+			logs.Errorf("failed to flush spans: %s", err)
+		}
+	}()
+
+	// Stop early on SIGINT (CTRL+C) and SIGTERM, the report is printed anyway.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runID := loadgen.NewRunID()
+	logs.Infow("starting load test",
+		"run_id", runID,
+		"target", cfg.Target,
+		"address", cfg.Address,
+		"concurrency", cfg.Concurrency,
+		"rate", cfg.Rate,
+		"duration", cfg.Duration.String(),
+	)
+
+	report := loadgen.NewRunner(cfg, runID, traceProvider, lib.CreateChildLogger(log, "runner")).Run(ctx)
+	report.Print(os.Stdout)
+	fmt.Printf("\nfind the traces in Tempo with: { span.loadgen.run_id = \"%s\" }\n", runID)
+
+	return nil
+}
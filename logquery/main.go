@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"observability-demo/internal/logquery"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func main() {
+	if err := run(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context) error {
+	var filter logquery.Filter
+	var level, components, since, until string
+	var follow, group bool
+	printer := &logquery.Printer{W: os.Stdout}
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: logquery [flags] [file ...]\n\n"+
+			"Reads the JSON logs of the services from the files or stdin and prints the matching entries.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&level, "level", "debug", "minimum level: debug, info, warn or error")
+	flag.StringVar(&components, "component", "", "comma separated components or services, e.g. service-1,store or service-2/http-server")
+	flag.StringVar(&since, "since", "", "only entries after the time, RFC 3339 or a duration ago like 15m")
+	flag.StringVar(&until, "until", "", "only entries before the time, RFC 3339 or a duration ago like 5m")
+	flag.StringVar(&filter.TraceID, "trace", "", "only entries of the trace ID")
+	flag.Func("where", "field expression, can be repeated: key=value, key!=value, key~regexp, key!~regexp, key>n, key<=n, e.g. status>=500 or duration>10ms", func(s string) error {
+		expr, err := logquery.ParseExpr(s)
+		if err != nil {
+			return err
+		}
+		filter.Exprs = append(filter.Exprs, expr)
+		return nil
+	})
+	flag.BoolVar(&follow, "f", false, "follow the files like tail -f")
+	flag.BoolVar(&group, "group", false, "group the entries by trace to show the story of each request")
+	flag.BoolVar(&printer.Raw, "raw", false, "print the matching entries as JSON")
+	flag.BoolVar(&printer.Caller, "caller", false, "print the caller of each entry")
+	flag.BoolVar(&printer.Color, "color", isTerminal(os.Stdout), "color the levels")
+	flag.Parse()
+
+	minLevel, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	filter.MinLevel = &minLevel
+	if components != "" {
+		filter.Components = strings.Split(components, ",")
+	}
+	if filter.Since, err = parseTime(since); err != nil {
+		return fmt.Errorf("invalid -since: %w", err)
+	}
+	if filter.Until, err = parseTime(until); err != nil {
+		return fmt.Errorf("invalid -until: %w", err)
+	}
+
+	files := flag.Args()
+	if follow && group {
+		return errors.New("-group needs all entries of a trace and can't be combined with -f")
+	}
+	if follow && len(files) == 0 {
+		return errors.New("-f needs at least one file")
+	}
+
+	if follow {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return followFiles(ctx, files, filter, printer)
+	}
+
+	entries, err := readFiles(files, filter, printer, !group && len(files) <= 1)
+	if err != nil {
+		return err
+	}
+
+	if group {
+		for _, t := range logquery.Group(entries) {
+			printer.PrintTrace(t)
+		}
+		return nil
+	}
+	// Entries of several files are merged by time.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	for _, e := range entries {
+		printer.Print(e)
+	}
+
+	return nil
+}
+
+// readFiles reads the matching entries of the files or stdin.
+// If stream is set, they are printed right away instead of being returned.
+func readFiles(files []string, filter logquery.Filter, printer *logquery.Printer, stream bool) ([]logquery.Entry, error) {
+	var entries []logquery.Entry
+	collect := func(e logquery.Entry) {
+		if !filter.Match(e) {
+			return
+		}
+		if stream {
+			printer.Print(e)
+			return
+		}
+		entries = append(entries, e)
+	}
+
+	read := func(name string, r io.Reader) error {
+		skipped, err := logquery.Read(r, collect)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if skipped > 0 {
+			fmt.Fprintf(os.Stderr, "skipped %d lines of %s which are not JSON log entries\n", skipped, name)
+		}
+		return nil
+	}
+
+	if len(files) == 0 {
+		return entries, read("stdin", os.Stdin)
+	}
+
+	for _, name := range files {
+		if name == "-" {
+			if err := read("stdin", os.Stdin); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		err = read(name, f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// followFiles prints the matching entries of all files as they are written until ctx is done.
+func followFiles(ctx context.Context, files []string, filter logquery.Filter, printer *logquery.Printer) error {
+	var mu sync.Mutex
+	printEntry := func(e logquery.Entry) {
+		if !filter.Match(e) {
+			return
+		}
+		mu.Lock()
+		printer.Print(e)
+		mu.Unlock()
+	}
+
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for i, name := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := logquery.Follow(ctx, name, printEntry); err != nil {
+				errs[i] = fmt.Errorf("failed to follow %s: %w", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// parseTime parses an RFC 3339 time or a duration before now, an empty string is the zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -14,27 +17,71 @@ var (
 		Name: "healthz_calls_total",
 		Help: "Total number of calls to the healthz endpoint.",
 	})
+	healthzDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "healthz_duration_seconds",
+		Help:    "Duration of the calls to the healthz endpoint.",
+		Buckets: prometheus.DefBuckets,
+	})
 )
 
 func init() {
-	prometheus.MustRegister(healthzCounter)
+	prometheus.MustRegister(healthzCounter, healthzDuration)
 }
 
-func main() {
-	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		healthzCounter.Inc()
+// exemplar returns the trace ID of the span in ctx as exemplar labels, or nil if the span is not sampled.
+// Unsampled traces are never stored, so an exemplar pointing to them would lead nowhere.
+func exemplar(ctx context.Context) prometheus.Labels {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsSampled() {
+		return nil
+	}
+
+	return prometheus.Labels{"trace_id": spanCtx.TraceID().String()}
+}
+
+// newHandler serves the healthz endpoint, whose calls are traced by tracer, and the metrics of gatherer.
+func newHandler(tracer trace.Tracer, gatherer prometheus.Gatherer) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, span := tracer.Start(r.Context(), "healthz")
+		defer span.End()
+
 		w.WriteHeader(http.StatusOK)
-		fmt.Println("Monitoring endpoint invoked! Counter was incremented!")
+
+		if labels := exemplar(ctx); labels != nil {
+			healthzCounter.(prometheus.ExemplarAdder).AddWithExemplar(1, labels)
+			healthzDuration.(prometheus.ExemplarObserver).ObserveWithExemplar(time.Since(start).Seconds(), labels)
+		} else {
+			healthzCounter.Inc()
+			healthzDuration.Observe(time.Since(start).Seconds())
+		}
+		fmt.Printf("Monitoring endpoint invoked! Counter was incremented! trace_id=%s sampled=%t\n",
+			span.SpanContext().TraceID(), span.SpanContext().IsSampled())
 	})
-	http.Handle("/metrics", promhttp.Handler())
+	// Exemplars are only part of the OpenMetrics format, which the handler serves if the scraper asks for it.
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	}))
+
+	return mux
+}
+
+func main() {
+	// Sample half of the traces, so both calls with and without exemplar can be seen.
+	traceProvider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.TraceIDRatioBased(0.5)))
+	defer func() {
+		_ = traceProvider.Shutdown(context.Background())
+	}()
 
 	server := &http.Server{
 		Addr:         ":1338",
+		Handler:      newHandler(traceProvider.Tracer("prometheus-example"), prometheus.DefaultGatherer),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
-	fmt.Println("Server listening on port 8080...")
+	fmt.Println("Server listening on port 1338...")
 	if err := server.ListenAndServe(); err != nil {
 		fmt.Printf("Error starting server: %s\n", err)
 	}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// exemplarTraceID matches the trace ID in the exemplar of a sample, e.g.
+// healthz_calls_total 1.0 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 1.0 1.7e+09
+var exemplarTraceID = regexp.MustCompile(` # \{(?:[^}]*,)?trace_id="([0-9a-f]{32})"`)
+
+// fixedIDs lets the test know the trace ID of a request, unsampled spans are never exported.
+type fixedIDs struct {
+	traceID trace.TraceID
+}
+
+func (g fixedIDs) NewIDs(context.Context) (trace.TraceID, trace.SpanID) {
+	return g.traceID, trace.SpanID{1}
+}
+
+func (g fixedIDs) NewSpanID(context.Context, trace.TraceID) trace.SpanID {
+	return trace.SpanID{1}
+}
+
+func TestHealthzExemplars(t *testing.T) {
+	sampled := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35}
+	unsampled := trace.TraceID{0x0a, 0xf7, 0x65, 0x19}
+
+	// The unsampled call comes last, an exemplar of it would replace the one of the sampled call.
+	for _, call := range []struct {
+		traceID trace.TraceID
+		sampler sdktrace.Sampler
+	}{
+		{sampled, sdktrace.AlwaysSample()},
+		{unsampled, sdktrace.NeverSample()},
+	} {
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(call.sampler), sdktrace.WithIDGenerator(fixedIDs{call.traceID}))
+		t.Cleanup(func() {
+			_ = tp.Shutdown(context.Background())
+		})
+		rec := httptest.NewRecorder()
+		newHandler(tp.Tracer("test"), prometheus.DefaultGatherer).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+	}
+
+	srv := httptest.NewServer(newHandler(noop.NewTracerProvider().Tracer("test"), prometheus.DefaultGatherer))
+	t.Cleanup(srv.Close)
+	exposition := scrapeOpenMetrics(t, srv.URL+"/metrics")
+
+	for _, metric := range []string{"healthz_calls_total", "healthz_duration_seconds"} {
+		traceIDs := exemplars(exposition, metric)
+		if !slices.Contains(traceIDs, sampled.String()) {
+			t.Errorf("metric %s has no exemplar with the sampled trace ID %s, got %v", metric, sampled, traceIDs)
+		}
+		if slices.Contains(traceIDs, unsampled.String()) {
+			t.Errorf("metric %s has an exemplar with the unsampled trace ID %s", metric, unsampled)
+		}
+	}
+}
+
+// scrapeOpenMetrics fetches the metrics from url in the OpenMetrics format, the Prometheus text format has no exemplars.
+func scrapeOpenMetrics(t *testing.T, url string) string {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to scrape %s: %s", url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/openmetrics-text") {
+		t.Fatalf("%s did not respond with OpenMetrics, got %q", url, resp.Header.Get("Content-Type"))
+	}
+
+	return string(body)
+}
+
+// exemplars returns the trace IDs of the exemplars of a metric, the buckets of a histogram included.
+func exemplars(exposition, metric string) []string {
+	var traceIDs []string
+	for _, line := range strings.Split(exposition, "\n") {
+		name, _, _ := strings.Cut(line, "{")
+		name, _, _ = strings.Cut(name, " ")
+		if name != metric && name != metric+"_bucket" {
+			continue
+		}
+		if match := exemplarTraceID.FindStringSubmatch(line); match != nil {
+			traceIDs = append(traceIDs, match[1])
+		}
+	}
+
+	return traceIDs
+}
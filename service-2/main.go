@@ -3,97 +3,94 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
+	"observability-demo/internal/service2"
 	"observability-demo/lib"
+	"observability-demo/lib/admin"
+	"observability-demo/lib/health"
+	"observability-demo/lib/profiling"
 	"os"
-	"sync"
 	"time"
-
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-const HTTPPort = "4041"
-
-func NewServer(controller *Controller) http.Handler {
-	mux := http.NewServeMux()
-
-	// handleFunc is a replacement for mux.HandleFunc
-	// which enriches the handler's HTTP instrumentation with the pattern as the http.route.
-	handleFunc := func(pattern string, handlerFunc func(http.ResponseWriter, *http.Request)) {
-		// Configure the "http.route" for the HTTP instrumentation.
-		handler := otelhttp.WithRouteTag(pattern, http.HandlerFunc(handlerFunc))
-		mux.Handle(pattern, handler)
-	}
-
-	handleFunc("/", controller.ServeHTTP)
-
-	// Add HTTP instrumentation for the whole server.
-	handler := otelhttp.NewHandler(mux, "/")
-	return handler
-
-}
+const (
+	HTTPPort = "4041"
+	GRPCPort = "4042"
+)
 
 func run(ctx context.Context) error {
 	lib.SetRuntimeSettings("service-2")
-	traceProvider, err := lib.GetTracer(context.Background(), lib.Backend)
+	cfg, err := service2.LoadConfig()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	baggageCfg, err := lib.LoadBaggageConfig()
+	if err != nil {
+		return err
+	}
+	profilingCfg, err := profiling.LoadConfig()
+	if err != nil {
+		return err
 	}
-	defer func() {
-		if err := traceProvider.Shutdown(context.Background()); err != nil {
-			log.Printf("Error shutting down tracer provider: %v", err)
-		}
-	}()
 
 	log := lib.CreateProductionLogger("service-2")
-	defer func() {
-		err := log.Sync()
-		if err != nil {
-			log.Fatal("Error syncing logger")
-		}
-	}()
 	logs := log.Sugar()
 
-	httpSrvLogger := lib.CreateChildLogger(log, "http-server")
-	storeLogger := lib.CreateChildLogger(log, "store")
+	// Handle SIGINT (CTRL+C) and SIGTERM gracefully.
+	lifecycle := lib.NewLifecycle(ctx, log, 10*time.Second)
 
-	store := NewMemoryStore(traceProvider.Tracer("store"), storeLogger)
-	controller := NewController(traceProvider.Tracer("controller"), store, httpSrvLogger)
-	srv := NewServer(controller)
-
-	// Handle SIGINT (CTRL+C) gracefully.
-	// ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	// defer stop()
+	traceProvider, err := lib.GetTracer(context.Background(), lib.Backend)
+	if err != nil {
+		logs.Fatal(err)
+	}
+	lifecycle.OnShutdown("tracer provider", lib.ShutdownTracerProvider(traceProvider))
+
+	registry := lib.NewRegistry()
+	svc := service2.New(cfg, lib.Telemetry{
+		Logger:         log,
+		TracerProvider: traceProvider,
+		Registry:       registry,
+		Baggage:        lib.NewBaggage(baggageCfg),
+	})
+	svc.Health.Register("lifecycle", lifecycle.CheckRunning, health.WithCacheTTL(0))
+
+	profiler, err := profiling.New("service-2", profilingCfg, registry, lib.CreateChildLogger(log, "profiling"))
+	if err != nil {
+		return err
+	}
+	go profiler.Run(lifecycle.Context())
+	if cfg.Store.TTL > 0 {
+		go svc.Store.ExpireLoop(lifecycle.Context(), cfg.Store.TTL)
+	}
 
 	httpServer := &http.Server{
 		Addr:         net.JoinHostPort("0.0.0.0", HTTPPort),
 		BaseContext:  func(_ net.Listener) context.Context { return ctx },
 		ReadTimeout:  time.Second,
 		WriteTimeout: 10 * time.Second,
-		Handler:      srv,
+		Handler:      svc.Handler,
 	}
-	go func() {
-		logs.Infof("listening on %s", httpServer.Addr)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logs.Fatalf("error listening and serving: %s", err)
+	lifecycle.AddHTTPServer("http server", httpServer)
+	lifecycle.AddGRPCServer("grpc server", svc.GRPCServer, net.JoinHostPort("0.0.0.0", GRPCPort))
+
+	if cfg.AdminAddress != "" {
+		adminServer := &http.Server{
+			Addr:        cfg.AdminAddress,
+			ReadTimeout: time.Second,
+			// CPU profiles and execution traces take 30 seconds by default.
+			WriteTimeout: 2 * time.Minute,
+			Handler: admin.NewHandler(admin.Options{
+				Service:  "service-2",
+				Config:   map[string]any{"service": cfg, "baggage": baggageCfg, "profiling": profilingCfg},
+				Routes:   svc.Routes,
+				Profiler: profiler,
+			}),
 		}
-	}()
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		<-ctx.Done()
+		lifecycle.AddHTTPServer("admin server", adminServer)
+	}
 
-		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-		defer cancel()
-		if err := httpServer.Shutdown(shutdownCtx); err != nil {
-			logs.Errorf("error shutting down http server: %s", err)
-		}
-	}()
-	wg.Wait()
-	return nil
+	return lifecycle.Run()
 }
 
 func main() {
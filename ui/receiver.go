@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"observability-demo/lib"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// addReceiver adds the local OTLP/HTTP receiver of the trace viewer to the lifecycle.
+// It has to be called after the shutdown hook of the tracer provider was added.
+// If the receiver can't listen, e.g. because Tempo already uses the port, the viewer is served anyway.
+func addReceiver(lifecycle *lib.Lifecycle, addr string, handler http.Handler, log *zap.SugaredLogger) {
+	receiver := &http.Server{
+		Addr:        addr,
+		ReadTimeout: 10 * time.Second,
+		Handler:     handler,
+	}
+	lifecycle.AddServer(
+		"otlp receiver",
+		func() error {
+			log.Infof("starting local OTLP receiver on %s", receiver.Addr)
+			if err := receiver.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Warnf("local OTLP receiver disabled: %s", err)
+			}
+			return nil
+		},
+		func(_ context.Context) error {
+			return nil
+		},
+	)
+	// The ui exports its own spans to the receiver, keep it running until the tracer provider is flushed.
+	lifecycle.OnShutdown("otlp receiver", receiver.Shutdown)
+}